@@ -0,0 +1,176 @@
+package arrowio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+type InsertOptions struct {
+	// 时间戳列的列名，为空时使用 DefaultTimeColumn。时间戳列的类型只能为 arrow.TIMESTAMP 或 arrow.INT64，且不能为空
+	TimeColumn string
+	// 设置后作为每一列路径的前缀
+	Prefix string
+}
+
+// Insert 通过 InsertColumnRecords 写入一个 Record，时间戳列以外的每一列对应一个序列，列名为路径，列的元数据为标签。
+// 空值不写入，整列为空的列被忽略
+func Insert(ctx context.Context, c client.Client, record array.Record, options InsertOptions) error {
+	if options.TimeColumn == "" {
+		options.TimeColumn = DefaultTimeColumn
+	}
+	schema := record.Schema()
+	timeIndices := schema.FieldIndices(options.TimeColumn)
+	if len(timeIndices) != 1 {
+		return fmt.Errorf("record should have exactly one time column %s", options.TimeColumn)
+	}
+	timeColumn := timeIndices[0]
+	timestamps, err := timestampsOf(record.Column(timeColumn))
+	if err != nil {
+		return err
+	}
+	if len(timestamps) == 0 {
+		return nil
+	}
+
+	// 按时间戳排序后写入
+	rowIndex := make([]int, len(timestamps))
+	for i := range rowIndex {
+		rowIndex[i] = i
+	}
+	sort.SliceStable(rowIndex, func(i, j int) bool {
+		return timestamps[rowIndex[i]] < timestamps[rowIndex[j]]
+	})
+	sortedTimestamps := make([]int64, len(timestamps))
+	for i, index := range rowIndex {
+		sortedTimestamps[i] = timestamps[index]
+	}
+
+	type column struct {
+		path     string
+		tags     map[string]string
+		dataType rpc.DataType
+		values   []interface{}
+	}
+	var columns []column
+	for j, field := range schema.Fields() {
+		if j == timeColumn {
+			continue
+		}
+		dataType, err := DataType(field.Type)
+		if err != nil {
+			return fmt.Errorf("column %s: %v", field.Name, err)
+		}
+		arr := record.Column(j)
+		if arr.NullN() == arr.Len() {
+			continue
+		}
+		values := make([]interface{}, len(rowIndex))
+		for i, index := range rowIndex {
+			values[i] = valueOf(arr, index)
+		}
+		path := field.Name
+		if options.Prefix != "" {
+			path = options.Prefix + "." + path
+		}
+		columns = append(columns, column{
+			path:     path,
+			tags:     metadataTags(field.Metadata),
+			dataType: dataType,
+			values:   values,
+		})
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columns[i].path < columns[j].path
+	})
+	if len(columns) == 0 {
+		return nil
+	}
+
+	paths := make([]string, len(columns))
+	types := make([]rpc.DataType, len(columns))
+	valueList := make([][]interface{}, len(columns))
+	tagsList := make([]map[string]string, len(columns))
+	hasTags := false
+	for i, col := range columns {
+		paths[i], types[i], valueList[i], tagsList[i] = col.path, col.dataType, col.values, col.tags
+		if len(col.tags) > 0 {
+			hasTags = true
+		}
+	}
+	if !hasTags {
+		tagsList = nil
+	}
+	return c.InsertColumnRecordsContext(ctx, paths, sortedTimestamps, valueList, types, tagsList)
+}
+
+// InsertReader 依次写入 reader 中的每个 Record，遇到第一个错误时停止
+func InsertReader(ctx context.Context, c client.Client, reader array.RecordReader, options InsertOptions) error {
+	for reader.Next() {
+		if err := Insert(ctx, c, reader.Record(), options); err != nil {
+			return err
+		}
+	}
+	if r, ok := reader.(interface{ Err() error }); ok {
+		return r.Err()
+	}
+	return nil
+}
+
+func timestampsOf(arr array.Interface) ([]int64, error) {
+	if arr.NullN() > 0 {
+		return nil, errors.New("time column should not have null values")
+	}
+	switch arr := arr.(type) {
+	case *array.Timestamp:
+		timestamps := make([]int64, arr.Len())
+		for i := range timestamps {
+			timestamps[i] = int64(arr.Value(i))
+		}
+		return timestamps, nil
+	case *array.Int64:
+		return append([]int64(nil), arr.Int64Values()...), nil
+	}
+	return nil, errors.New("time column should be TIMESTAMP or INT64, but got " + arr.DataType().Name())
+}
+
+// valueOf 返回第 i 行的值，空值为 nil
+func valueOf(arr array.Interface, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch arr := arr.(type) {
+	case *array.Boolean:
+		return arr.Value(i)
+	case *array.Int32:
+		return arr.Value(i)
+	case *array.Int64:
+		return arr.Value(i)
+	case *array.Float32:
+		return arr.Value(i)
+	case *array.Float64:
+		return arr.Value(i)
+	case *array.String:
+		return arr.Value(i)
+	case *array.Binary:
+		return string(arr.Value(i))
+	}
+	return nil
+}
+
+func metadataTags(metadata arrow.Metadata) map[string]string {
+	if metadata.Len() == 0 {
+		return nil
+	}
+	tags := make(map[string]string, metadata.Len())
+	for i, key := range metadata.Keys() {
+		tags[key] = metadata.Values()[i]
+	}
+	return tags
+}
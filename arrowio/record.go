@@ -0,0 +1,289 @@
+// Package arrowio 在 IginX 的查询结果与 Apache Arrow 的 Record 之间转换。
+//
+// Record 的第一列为时间戳列，其余每列对应一个序列，列名为序列的路径，序列的标签保存在列的元数据中。
+// 所有列都可以为空，空值对应 Arrow 的 validity bitmap。数据类型的对应关系为：
+//
+//	BOOLEAN  arrow.BOOL
+//	INTEGER  arrow.INT32
+//	LONG     arrow.INT64
+//	FLOAT    arrow.FLOAT32
+//	DOUBLE   arrow.FLOAT64
+//	BINARY   arrow.STRING
+package arrowio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// DefaultTimeColumn 是时间戳列的列名
+const DefaultTimeColumn = "key"
+
+type Options struct {
+	// 时间戳列的列名，为空时使用 DefaultTimeColumn
+	TimeColumn string
+	// 时间戳列的类型，只能为 arrow.TIMESTAMP 或 arrow.INT64，为 nil 时为毫秒精度的 arrow.TIMESTAMP。
+	// 时间戳按原值写入，不做单位换算
+	TimeType arrow.DataType
+}
+
+func (o Options) withDefaults() (Options, error) {
+	if o.TimeColumn == "" {
+		o.TimeColumn = DefaultTimeColumn
+	}
+	if o.TimeType == nil {
+		o.TimeType = arrow.FixedWidthTypes.Timestamp_ms
+	}
+	if id := o.TimeType.ID(); id != arrow.TIMESTAMP && id != arrow.INT64 {
+		return o, errors.New("time type should be TIMESTAMP or INT64, but got " + o.TimeType.Name())
+	}
+	return o, nil
+}
+
+// ArrowType 返回 IginX 数据类型对应的 Arrow 类型
+func ArrowType(dataType rpc.DataType) (arrow.DataType, error) {
+	switch dataType {
+	case rpc.DataType_BOOLEAN:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case rpc.DataType_INTEGER:
+		return arrow.PrimitiveTypes.Int32, nil
+	case rpc.DataType_LONG:
+		return arrow.PrimitiveTypes.Int64, nil
+	case rpc.DataType_FLOAT:
+		return arrow.PrimitiveTypes.Float32, nil
+	case rpc.DataType_DOUBLE:
+		return arrow.PrimitiveTypes.Float64, nil
+	case rpc.DataType_BINARY:
+		return arrow.BinaryTypes.String, nil
+	}
+	return nil, errors.New("unknown data type " + dataType.String())
+}
+
+// DataType 返回 Arrow 类型对应的 IginX 数据类型，arrow.BINARY 也对应 BINARY
+func DataType(arrowType arrow.DataType) (rpc.DataType, error) {
+	switch arrowType.ID() {
+	case arrow.BOOL:
+		return rpc.DataType_BOOLEAN, nil
+	case arrow.INT32:
+		return rpc.DataType_INTEGER, nil
+	case arrow.INT64:
+		return rpc.DataType_LONG, nil
+	case arrow.FLOAT32:
+		return rpc.DataType_FLOAT, nil
+	case arrow.FLOAT64:
+		return rpc.DataType_DOUBLE, nil
+	case arrow.STRING, arrow.BINARY:
+		return rpc.DataType_BINARY, nil
+	}
+	return 0, errors.New("unsupported arrow type " + arrowType.Name())
+}
+
+// NewSchema 返回由时间戳列和 paths 对应的列组成的 Schema，hasTime 为 false 时不包含时间戳列
+func NewSchema(paths []string, tags []map[string]string, types []rpc.DataType, hasTime bool, options Options) (*arrow.Schema, error) {
+	options, err := options.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != len(paths) {
+		return nil, fmt.Errorf("expect %d data types, but got %d", len(paths), len(types))
+	}
+	var fields []arrow.Field
+	if hasTime {
+		fields = append(fields, arrow.Field{Name: options.TimeColumn, Type: options.TimeType})
+	}
+	for i, path := range paths {
+		arrowType, err := ArrowType(types[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", path, err)
+		}
+		field := arrow.Field{Name: path, Type: arrowType, Nullable: true}
+		if i < len(tags) && len(tags[i]) > 0 {
+			field.Metadata = tagsMetadata(tags[i])
+		}
+		fields = append(fields, field)
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// FromQueryDataSet 将 Query、DownSampleQuery 或 LastQuery 的结果转换为一个 Record，调用方负责 Release
+func FromQueryDataSet(mem memory.Allocator, dataSet *client.QueryDataSet, options Options) (array.Record, error) {
+	schema, err := NewSchema(dataSet.Paths, dataSet.Tags, dataSet.Types, true, options)
+	if err != nil {
+		return nil, err
+	}
+	b := newRecordBuilder(mem, schema)
+	defer b.release()
+	for i, row := range dataSet.Values {
+		if i >= len(dataSet.Timestamps) {
+			return nil, fmt.Errorf("missing timestamp of row %d", i)
+		}
+		b.appendTime(dataSet.Timestamps[i])
+		for j := range dataSet.Paths {
+			var value interface{}
+			if j < len(row) {
+				value = row[j]
+			}
+			if err := b.append(j+1, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.newRecord(), nil
+}
+
+// FromAggregateQueryDataSet 将 AggregateQuery 的结果转换为一个 Record，调用方负责 Release。
+// 没有时间戳时 Record 只有一行且不包含时间戳列；FIRST、LAST 等为每个序列返回时间戳的聚合，每个序列一行，
+// 行中只有该序列对应的列不为空
+func FromAggregateQueryDataSet(mem memory.Allocator, dataSet *client.AggregateQueryDataSet, options Options) (array.Record, error) {
+	hasTime := dataSet.Timestamps != nil
+	schema, err := NewSchema(dataSet.Paths, dataSet.Tags, dataSet.Types, hasTime, options)
+	if err != nil {
+		return nil, err
+	}
+	b := newRecordBuilder(mem, schema)
+	defer b.release()
+	if !hasTime {
+		for j := range dataSet.Paths {
+			var value interface{}
+			if j < len(dataSet.Values) {
+				value = dataSet.Values[j]
+			}
+			if err := b.append(j, value); err != nil {
+				return nil, err
+			}
+		}
+		return b.newRecord(), nil
+	}
+
+	for i := range dataSet.Paths {
+		if i >= len(dataSet.Timestamps) || i >= len(dataSet.Values) {
+			return nil, fmt.Errorf("missing result of column %s", dataSet.Paths[i])
+		}
+		b.appendTime(dataSet.Timestamps[i])
+		for j := range dataSet.Paths {
+			var value interface{}
+			if j == i {
+				value = dataSet.Values[i]
+			}
+			if err := b.append(j+1, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.newRecord(), nil
+}
+
+func tagsMetadata(tags map[string]string) arrow.Metadata {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = tags[k]
+	}
+	return arrow.NewMetadata(keys, values)
+}
+
+// recordBuilder 逐行构造 Record，时间戳列的下标为 0
+type recordBuilder struct {
+	schema   *arrow.Schema
+	builders []array.Builder
+}
+
+func newRecordBuilder(mem memory.Allocator, schema *arrow.Schema) *recordBuilder {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	b := &recordBuilder{schema: schema}
+	for _, field := range schema.Fields() {
+		b.builders = append(b.builders, array.NewBuilder(mem, field.Type))
+	}
+	return b
+}
+
+func (b *recordBuilder) appendTime(timestamp int64) {
+	switch builder := b.builders[0].(type) {
+	case *array.TimestampBuilder:
+		builder.Append(arrow.Timestamp(timestamp))
+	case *array.Int64Builder:
+		builder.Append(timestamp)
+	}
+}
+
+// append 向第 i 列追加一个值，nil 为空值
+func (b *recordBuilder) append(i int, value interface{}) error {
+	if value == nil {
+		b.builders[i].AppendNull()
+		return nil
+	}
+	ok := false
+	switch builder := b.builders[i].(type) {
+	case *array.BooleanBuilder:
+		var v bool
+		if v, ok = value.(bool); ok {
+			builder.Append(v)
+		}
+	case *array.Int32Builder:
+		var v int32
+		if v, ok = value.(int32); ok {
+			builder.Append(v)
+		}
+	case *array.Int64Builder:
+		var v int64
+		if v, ok = value.(int64); ok {
+			builder.Append(v)
+		}
+	case *array.Float32Builder:
+		var v float32
+		if v, ok = value.(float32); ok {
+			builder.Append(v)
+		}
+	case *array.Float64Builder:
+		var v float64
+		if v, ok = value.(float64); ok {
+			builder.Append(v)
+		}
+	case *array.StringBuilder:
+		var v string
+		if v, ok = value.(string); ok {
+			builder.Append(v)
+		}
+	}
+	if !ok {
+		field := b.schema.Field(i)
+		return fmt.Errorf("value %v of column %s is %T, but %s is expected", value, field.Name, value, field.Type.Name())
+	}
+	return nil
+}
+
+// newRecord 用已追加的值构造 Record 并重置所有列
+func (b *recordBuilder) newRecord() array.Record {
+	columns := make([]array.Interface, len(b.builders))
+	for i, builder := range b.builders {
+		columns[i] = builder.NewArray()
+	}
+	var rows int64
+	if len(columns) > 0 {
+		rows = int64(columns[0].Len())
+	}
+	record := array.NewRecord(b.schema, columns, rows)
+	for _, column := range columns {
+		column.Release()
+	}
+	return record
+}
+
+func (b *recordBuilder) release() {
+	for _, builder := range b.builders {
+		builder.Release()
+	}
+}
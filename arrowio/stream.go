@@ -0,0 +1,159 @@
+package arrowio
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// StreamReader 将 ExecuteQuery 的结果按拉取的批次转换为 Record，每次拉取得到的行对应一个 Record，实现了 array.RecordReader：
+//
+//	reader, err := arrowio.NewStreamReader(memory.DefaultAllocator, dataSet, arrowio.Options{})
+//	defer reader.Release()
+//	for reader.Next() {
+//		record := reader.Record()
+//	}
+//	if err := reader.Err(); err != nil {
+//	}
+//
+// 使用执行查询时的 context 拉取数据，结果中没有时间戳列时 Record 也不包含时间戳列
+type StreamReader struct {
+	refCount int64
+	dataSet  *client.StreamDataSet
+	schema   *arrow.Schema
+	builder  *recordBuilder
+	// fields 为结果中每一列在 Record 中的下标
+	fields []int
+	record array.Record
+	err    error
+}
+
+var _ array.RecordReader = (*StreamReader)(nil)
+
+// NewStreamReader 返回读取 dataSet 的 StreamReader，Release 时关闭 dataSet
+func NewStreamReader(mem memory.Allocator, dataSet *client.StreamDataSet, options Options) (*StreamReader, error) {
+	columns, types, tags := dataSet.GetColumns(), dataSet.GetDataTypes(), dataSet.GetTags()
+	if len(types) != len(columns) {
+		return nil, errors.New("columns and data types are not matched")
+	}
+	timeColumn := dataSet.GetTimeColumn()
+	fields := make([]int, len(columns))
+	var paths []string
+	var dataTypes []rpc.DataType
+	var valueTags []map[string]string
+	for _, column := range dataSet.ColumnsByTags(nil) {
+		fields[column] = len(paths)
+		if timeColumn >= 0 {
+			fields[column]++
+		}
+		paths = append(paths, columns[column])
+		dataTypes = append(dataTypes, types[column])
+		if tags != nil {
+			var columnTags map[string]string
+			if column < len(tags) {
+				columnTags = tags[column]
+			}
+			valueTags = append(valueTags, columnTags)
+		}
+	}
+
+	schema, err := NewSchema(paths, valueTags, dataTypes, timeColumn >= 0, options)
+	if err != nil {
+		return nil, err
+	}
+	return &StreamReader{
+		refCount: 1,
+		dataSet:  dataSet,
+		schema:   schema,
+		builder:  newRecordBuilder(mem, schema),
+		fields:   fields,
+	}, nil
+}
+
+func (r *StreamReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+// Release 减少引用计数，为 0 时释放当前的 Record 并关闭查询
+func (r *StreamReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+	if r.record != nil {
+		r.record.Release()
+		r.record = nil
+	}
+	r.builder.release()
+	_ = r.dataSet.Close()
+}
+
+func (r *StreamReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Next 拉取下一批数据并转换为 Record，之前返回的 Record 会被释放，需要继续使用时应调用 Retain。
+// 没有更多数据或出错时返回 false
+func (r *StreamReader) Next() bool {
+	if r.record != nil {
+		r.record.Release()
+		r.record = nil
+	}
+	if r.err != nil {
+		return false
+	}
+	valuesList, bitmapList := r.dataSet.NextBatch()
+	if valuesList == nil {
+		return false
+	}
+	if r.record, r.err = r.convert(valuesList, bitmapList); r.err != nil {
+		_ = r.dataSet.Close()
+		return false
+	}
+	return true
+}
+
+// convert 按每行的 Bitmap 解码一批数据，Bitmap 中为空的值在 Record 中也为空
+func (r *StreamReader) convert(valuesList, bitmapList [][]byte) (array.Record, error) {
+	types := r.dataSet.GetDataTypes()
+	timeColumn := r.dataSet.GetTimeColumn()
+	for i := range valuesList {
+		valuesBuffer := valuesList[i]
+		bitmap := client.NewBitmapWithBuf(len(types), bitmapList[i])
+		for j := range types {
+			var value interface{}
+			if notNil, _ := bitmap.Get(j); notNil {
+				value, valuesBuffer = client.GetValueFromBytes(valuesBuffer, types[j])
+			}
+			if j == timeColumn {
+				timestamp, ok := value.(int64)
+				if !ok {
+					return nil, errors.New("missing timestamp")
+				}
+				r.builder.appendTime(timestamp)
+				continue
+			}
+			if err := r.builder.append(r.fields[j], value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r.builder.newRecord(), nil
+}
+
+// Record 返回 Next 转换的当前 Record，在下一次调用 Next 之前有效
+func (r *StreamReader) Record() array.Record {
+	return r.record
+}
+
+// Err 返回转换或拉取数据的错误，正常读完时返回 nil
+func (r *StreamReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.dataSet.Err()
+}
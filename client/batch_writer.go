@@ -0,0 +1,425 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+const (
+	DefaultBatchSize       = 1000
+	DefaultFlushInterval   = time.Second
+	DefaultMaxBufferedRows = 10000
+)
+
+var ErrWriterClosed = errors.New("batch writer is closed")
+
+// RecordInserter 是 BatchWriter 写入数据时使用的接口，*Session 和 *SessionPool 均实现了该接口
+type RecordInserter interface {
+	InsertRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+}
+
+type BatchWriterConfig struct {
+	// 同一组序列缓存的行数达到该值后立即写入
+	BatchSize int
+	// 每隔该时长写入所有缓存的数据
+	FlushInterval time.Duration
+	// 所有序列缓存的总行数上限，达到上限后写入会阻塞直到缓存被写出
+	MaxBufferedRows int
+	// 写入的超时时间，为 0 时不超时
+	FlushTimeout time.Duration
+	// 使用非对齐的方式写入
+	NonAligned bool
+	// 后台写入失败时调用，错误类型为 *BatchError。为 nil 时错误发送到 Errors 返回的通道，
+	// 通道已满时丢弃的错误由下一次 Flush 或 Close 以 *DroppedError 返回
+	ErrorHandler func(err error)
+}
+
+// BatchError 记录一次失败的批量写入，调用方可以据此重新写入这部分数据
+type BatchError struct {
+	Paths        []string
+	Timestamps   []int64
+	ValueList    [][]interface{}
+	DataTypeList []rpc.DataType
+	TagsList     []map[string]string
+	Err          error
+}
+
+func (e *BatchError) Error() string {
+	return "fail to write " + strconv.Itoa(len(e.Timestamps)) + " rows of " + strings.Join(e.Paths, ", ") + ": " + e.Err.Error()
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// DroppedError 表示因 Errors 返回的通道已满而没有送达的后台写入错误，Err 为其中的第一个
+type DroppedError struct {
+	Count int
+	Err   error
+}
+
+func (e *DroppedError) Error() string {
+	return strconv.Itoa(e.Count) + " background write errors were dropped, the first one: " + e.Err.Error()
+}
+
+func (e *DroppedError) Unwrap() error {
+	return e.Err
+}
+
+type rowBatch struct {
+	paths      []string
+	types      []rpc.DataType
+	tags       []map[string]string
+	timestamps []int64
+	values     [][]interface{}
+}
+
+// BatchWriter 按序列分组缓存写入的数据点，在缓存达到 BatchSize 或每隔 FlushInterval 时在后台批量写入，
+// 可以被多个 goroutine 并发使用
+type BatchWriter struct {
+	inserter RecordInserter
+	config   BatchWriterConfig
+
+	mu      sync.Mutex
+	batches map[string]*rowBatch
+	isClose bool
+
+	// 同一时刻只有一个写入在进行，保证 *Session 不会被并发使用
+	flushMu sync.Mutex
+	// 每缓存一行占用一个位置，写出后释放
+	slots  chan struct{}
+	full   chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+	errors chan error
+	// 没有送达 errors 的错误，由 Flush 返回
+	dropped *DroppedError
+}
+
+func NewBatchWriter(inserter RecordInserter, config BatchWriterConfig) *BatchWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.MaxBufferedRows <= 0 {
+		config.MaxBufferedRows = DefaultMaxBufferedRows
+	}
+	if config.MaxBufferedRows < config.BatchSize {
+		config.MaxBufferedRows = config.BatchSize
+	}
+
+	w := &BatchWriter{
+		inserter: inserter,
+		config:   config,
+		batches:  make(map[string]*rowBatch),
+		slots:    make(chan struct{}, config.MaxBufferedRows),
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		errors:   make(chan error, 64),
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// Errors 返回后台写入失败的错误，仅在没有设置 ErrorHandler 时使用，通道在 Close 后关闭
+func (w *BatchWriter) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *BatchWriter) WritePoint(path string, timestamp int64, value interface{}, dataType rpc.DataType, tags map[string]string) error {
+	return w.WritePointContext(context.Background(), path, timestamp, value, dataType, tags)
+}
+
+func (w *BatchWriter) WritePointContext(ctx context.Context, path string, timestamp int64, value interface{}, dataType rpc.DataType, tags map[string]string) error {
+	var tagsList []map[string]string
+	if tags != nil {
+		tagsList = []map[string]string{tags}
+	}
+	return w.WriteRowContext(ctx, []string{path}, timestamp, []interface{}{value}, []rpc.DataType{dataType}, tagsList)
+}
+
+func (w *BatchWriter) WriteRow(paths []string, timestamp int64, values []interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return w.WriteRowContext(context.Background(), paths, timestamp, values, dataTypeList, tagsList)
+}
+
+func (w *BatchWriter) WriteRowContext(ctx context.Context, paths []string, timestamp int64, values []interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	if len(paths) == 0 {
+		return errors.New("invalid write request")
+	}
+	if len(paths) != len(values) || len(paths) != len(dataTypeList) {
+		return errors.New("the sizes of paths, values and dataTypeList should be equal")
+	}
+	if tagsList != nil && len(paths) != len(tagsList) {
+		return errors.New("the sizes of paths and tagsList should be equal")
+	}
+
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	w.mu.Lock()
+	if w.isClose {
+		w.mu.Unlock()
+		<-w.slots
+		return ErrWriterClosed
+	}
+	key := batchKey(paths, dataTypeList, tagsList)
+	batch, ok := w.batches[key]
+	if !ok {
+		batch = &rowBatch{
+			paths: append([]string(nil), paths...),
+			types: append([]rpc.DataType(nil), dataTypeList...),
+			tags:  append([]map[string]string(nil), tagsList...),
+		}
+		w.batches[key] = batch
+	}
+	batch.timestamps = append(batch.timestamps, timestamp)
+	batch.values = append(batch.values, append([]interface{}(nil), values...))
+	isFull := len(batch.timestamps) >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if isFull {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush 立即写入所有缓存的数据，返回第一个写入错误。没有写入错误时，返回之前因通道已满而丢弃的后台写入错误
+func (w *BatchWriter) Flush() error {
+	return w.FlushContext(context.Background())
+}
+
+func (w *BatchWriter) FlushContext(ctx context.Context) error {
+	var firstErr error
+	for _, err := range w.flush(ctx, false) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dropped != nil {
+		firstErr = w.dropped
+		w.dropped = nil
+	}
+	return firstErr
+}
+
+// Close 停止后台写入并写出所有缓存的数据，此后的写入会返回 ErrWriterClosed
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	if w.isClose {
+		w.mu.Unlock()
+		return nil
+	}
+	w.isClose = true
+	w.mu.Unlock()
+
+	close(w.done)
+	w.wg.Wait()
+	err := w.Flush()
+	close(w.errors)
+	return err
+}
+
+func (w *BatchWriter) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.report(w.flush(context.Background(), false))
+		case <-w.full:
+			w.report(w.flush(context.Background(), true))
+		}
+	}
+}
+
+// flush 写出缓存的批次，onlyFull 为 true 时只写出达到 BatchSize 的批次
+func (w *BatchWriter) flush(ctx context.Context, onlyFull bool) []error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	var batches []*rowBatch
+	for key, batch := range w.batches {
+		if onlyFull && len(batch.timestamps) < w.config.BatchSize {
+			continue
+		}
+		batches = append(batches, batch)
+		delete(w.batches, key)
+	}
+	w.mu.Unlock()
+
+	var errs []error
+	for _, batch := range batches {
+		if err := w.write(ctx, batch); err != nil {
+			errs = append(errs, err)
+		}
+		for range batch.timestamps {
+			<-w.slots
+		}
+	}
+	return errs
+}
+
+func (w *BatchWriter) write(ctx context.Context, batch *rowBatch) error {
+	if w.config.FlushTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.config.FlushTimeout)
+		defer cancel()
+	}
+
+	// 插入接口会对传入的切片重新排序，且只在序列已经递增时保持值与序列的对应关系，
+	// 这里传入按时间戳、路径和标签排好序的副本，每一行的值按相同的顺序重排，出错时返回原始数据
+	order := make([]int, len(batch.timestamps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return batch.timestamps[order[i]] < batch.timestamps[order[j]]
+	})
+	columns := make([]int, len(batch.paths))
+	for i := range columns {
+		columns[i] = i
+	}
+	sort.SliceStable(columns, func(i, j int) bool {
+		a, b := columns[i], columns[j]
+		if batch.paths[a] != batch.paths[b] {
+			return batch.paths[a] < batch.paths[b]
+		}
+		return len(batch.tags) > 0 && tagsKey(batch.tags[a]) < tagsKey(batch.tags[b])
+	})
+
+	timestamps := make([]int64, len(order))
+	values := make([][]interface{}, len(order))
+	for i, index := range order {
+		timestamps[i] = batch.timestamps[index]
+		values[i] = make([]interface{}, len(columns))
+		for j, column := range columns {
+			values[i][j] = batch.values[index][column]
+		}
+	}
+	paths := make([]string, len(columns))
+	types := make([]rpc.DataType, len(columns))
+	var tags []map[string]string
+	if len(batch.tags) > 0 {
+		tags = make([]map[string]string, len(columns))
+	}
+	for j, column := range columns {
+		paths[j], types[j] = batch.paths[column], batch.types[column]
+		if tags != nil {
+			tags[j] = batch.tags[column]
+		}
+	}
+
+	var err error
+	if w.config.NonAligned {
+		err = w.inserter.InsertNonAlignedRowRecordsContext(ctx, paths, timestamps, values, types, tags)
+	} else {
+		err = w.inserter.InsertRowRecordsContext(ctx, paths, timestamps, values, types, tags)
+	}
+	if err != nil {
+		return &BatchError{
+			Paths:        batch.paths,
+			Timestamps:   batch.timestamps,
+			ValueList:    batch.values,
+			DataTypeList: batch.types,
+			TagsList:     batch.tags,
+			Err:          err,
+		}
+	}
+	return nil
+}
+
+func (w *BatchWriter) report(errs []error) {
+	for _, err := range errs {
+		if w.config.ErrorHandler != nil {
+			w.config.ErrorHandler(err)
+			continue
+		}
+		select {
+		case w.errors <- err:
+		default:
+			w.mu.Lock()
+			if w.dropped == nil {
+				w.dropped = &DroppedError{Err: err}
+			}
+			w.dropped.Count++
+			w.mu.Unlock()
+		}
+	}
+}
+
+// batchKey 由序列、数据类型和标签组成，相同 key 的行可以合并到同一次写入中。
+// 路径和标签的每一部分都加上引号，含有分隔符的路径或标签不会与其他序列得到相同的 key
+func batchKey(paths []string, types []rpc.DataType, tagsList []map[string]string) string {
+	var builder strings.Builder
+	for i, path := range paths {
+		builder.WriteString(strconv.Quote(path))
+		builder.WriteByte(':')
+		builder.WriteString(strconv.Itoa(int(types[i])))
+		if tagsList != nil {
+			tags := tagsList[i]
+			keys := make([]string, 0, len(tags))
+			for k := range tags {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			builder.WriteByte('{')
+			for _, k := range keys {
+				builder.WriteString(strconv.Quote(k))
+				builder.WriteByte('=')
+				builder.WriteString(strconv.Quote(tags[k]))
+				builder.WriteByte(',')
+			}
+			builder.WriteByte('}')
+		}
+		builder.WriteByte(';')
+	}
+	return builder.String()
+}
+
+func tagsKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	builder.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(k)
+		builder.WriteByte('=')
+		builder.WriteString(tags[k])
+	}
+	builder.WriteByte('}')
+	return builder.String()
+}
@@ -0,0 +1,132 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/iginxtest"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func TestBatchWriterDroppedErrors(t *testing.T) {
+	errWrite := errors.New("write failed")
+	mock := &iginxtest.MockClient{
+		InsertRowRecordsFunc: func(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+			return errWrite
+		},
+	}
+	writer := client.NewBatchWriter(mock, client.BatchWriterConfig{BatchSize: 1, FlushInterval: 10 * time.Millisecond})
+
+	// 每一行属于不同的序列，各自写入一次，错误数超过 Errors 通道的容量
+	const rows = 100
+	for i := 0; i < rows; i++ {
+		if err := writer.WritePoint("a.b"+strconv.Itoa(i), 1, int64(i), rpc.DataType_LONG, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mock.CallsTo("InsertRowRecords")) < rows {
+		if time.Now().After(deadline) {
+			t.Fatal("rows were not written in the background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err := writer.Close()
+	var dropped *client.DroppedError
+	if !errors.As(err, &dropped) {
+		t.Fatalf("Close: err = %v, want a DroppedError", err)
+	}
+	reported := len(writer.Errors())
+	if reported+dropped.Count != rows {
+		t.Errorf("reported %d and dropped %d errors, want %d in total", reported, dropped.Count, rows)
+	}
+	var batchErr *client.BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, errWrite) {
+		t.Errorf("dropped error %v does not wrap the BatchError", err)
+	}
+}
+
+func TestBatchWriterUnsortedPaths(t *testing.T) {
+	session := openSession(t, newServer(t))
+	writer := client.NewBatchWriter(session, client.BatchWriterConfig{FlushInterval: time.Hour})
+	types := []rpc.DataType{rpc.DataType_LONG, rpc.DataType_LONG, rpc.DataType_BINARY}
+	rows := []struct {
+		timestamp int64
+		values    []interface{}
+	}{
+		{20, []interface{}{int64(6), int64(4), "five"}},
+		{10, []interface{}{int64(3), int64(1), "two"}},
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow([]string{"t.c", "t.a", "t.b"}, row.timestamp, row.values, types, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dataSet, err := session.Query([]string{"t.*"}, 0, 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"t.a", "t.b", "t.c"}; !reflect.DeepEqual(dataSet.Paths, want) {
+		t.Fatalf("paths = %v, want %v", dataSet.Paths, want)
+	}
+	if want := []rpc.DataType{types[1], types[2], types[0]}; !reflect.DeepEqual(dataSet.Types, want) {
+		t.Errorf("types = %v, want %v", dataSet.Types, want)
+	}
+	want := [][]interface{}{{int64(1), "two", int64(3)}, {int64(4), "five", int64(6)}}
+	if !reflect.DeepEqual(dataSet.Timestamps, []int64{10, 20}) || !reflect.DeepEqual(dataSet.Values, want) {
+		t.Errorf("got %v %v, want [10 20] %v", dataSet.Timestamps, dataSet.Values, want)
+	}
+}
+
+func TestBatchWriterKeepsSeriesApart(t *testing.T) {
+	// 每组中的两次写入属于不同的序列，不能合并到同一次写入中
+	integer := strconv.Itoa(int(rpc.DataType_INTEGER))
+	tests := []struct {
+		name   string
+		writes [2]func(w *client.BatchWriter) error
+	}{
+		{"tags with separators", [2]func(w *client.BatchWriter) error{
+			func(w *client.BatchWriter) error {
+				return w.WritePoint("t.a", 1, int64(1), rpc.DataType_LONG, map[string]string{"a": "1,b=2"})
+			},
+			func(w *client.BatchWriter) error {
+				return w.WritePoint("t.a", 2, int64(2), rpc.DataType_LONG, map[string]string{"a": "1", "b": "2"})
+			},
+		}},
+		{"paths with separators", [2]func(w *client.BatchWriter) error{
+			func(w *client.BatchWriter) error {
+				return w.WriteRow([]string{"t.a:" + integer + ";t.b"}, 1, []interface{}{int64(1)}, []rpc.DataType{rpc.DataType_LONG}, nil)
+			},
+			func(w *client.BatchWriter) error {
+				return w.WriteRow([]string{"t.a", "t.b"}, 2, []interface{}{int32(1), int64(2)}, []rpc.DataType{rpc.DataType_INTEGER, rpc.DataType_LONG}, nil)
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &iginxtest.MockClient{}
+			writer := client.NewBatchWriter(mock, client.BatchWriterConfig{FlushInterval: time.Hour})
+			for _, write := range tt.writes {
+				if err := write(writer); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if calls := mock.CallsTo("InsertRowRecords"); len(calls) != 2 {
+				t.Errorf("%d inserts, want 2: %v", len(calls), calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var (
+	_ Client = (*Session)(nil)
+	_ Client = (*SessionPool)(nil)
+)
+
+// Client 是 Session 和 SessionPool 共同实现的读写、删除、管理和 SQL 接口，
+// 依赖该接口而不是具体类型，便于在测试中替换为 iginxtest.MockClient
+type Client interface {
+	ListTimeSeries() ([]TimeSeries, error)
+	ListTimeSeriesContext(ctx context.Context) ([]TimeSeries, error)
+
+	InsertRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertRecords(records ...*Record) error
+	InsertRecordsContext(ctx context.Context, records ...*Record) error
+	InsertStructs(prefix string, items interface{}) error
+	InsertStructsContext(ctx context.Context, prefix string, items interface{}) error
+
+	DeleteData(path string, startTime, endTime int64, tagsList map[string][]string) error
+	DeleteDataContext(ctx context.Context, path string, startTime, endTime int64, tagsList map[string][]string) error
+	BatchDeleteData(paths []string, startTime, endTime int64, tagsList map[string][]string) error
+	BatchDeleteDataContext(ctx context.Context, paths []string, startTime, endTime int64, tagsList map[string][]string) error
+	DeleteTimeSeries(path string) error
+	DeleteTimeSeriesContext(ctx context.Context, path string) error
+	BatchDeleteTimeSeries(paths []string) error
+	BatchDeleteTimeSeriesContext(ctx context.Context, paths []string) error
+
+	Query(paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error)
+	QueryContext(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error)
+	DownSampleQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error)
+	DownSampleQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error)
+	AggregateQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error)
+	AggregateQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error)
+	LastQuery(paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error)
+	LastQueryContext(ctx context.Context, paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error)
+	QueryStream(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string, options QueryStreamOptions) (*QueryStream, error)
+	CurveMatch(paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error)
+	CurveMatchContext(ctx context.Context, paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error)
+
+	ExecuteSQL(sql string) (*SQLDataSet, error)
+	ExecuteSQLContext(ctx context.Context, sql string) (*SQLDataSet, error)
+	ExecuteQuery(statement string) (*StreamDataSet, error)
+	ExecuteQueryContext(ctx context.Context, statement string) (*StreamDataSet, error)
+	ExecuteQueryWithFetchSize(statement string, fetchSize int32) (*StreamDataSet, error)
+	ExecuteQueryWithFetchSizeContext(ctx context.Context, statement string, fetchSize int32) (*StreamDataSet, error)
+
+	GetReplicaNum() (int32, error)
+	GetReplicaNumContext(ctx context.Context) (int32, error)
+	GetClusterInfo() (*ClusterInfo, error)
+	GetClusterInfoContext(ctx context.Context) (*ClusterInfo, error)
+	AddStorageEngine(ip, port, engineType string, extra map[string]string) error
+	AddStorageEngineContext(ctx context.Context, ip, port, engineType string, extra map[string]string) error
+	BatchAddStorageEngine(engines []*rpc.StorageEngine) error
+	BatchAddStorageEngineContext(ctx context.Context, engines []*rpc.StorageEngine) error
+
+	AddUser(username, password string, auths []rpc.AuthType) error
+	AddUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error
+	DeleteUser(username string) error
+	DeleteUserContext(ctx context.Context, username string) error
+	UpdateUser(username, password string, auths []rpc.AuthType) error
+	UpdateUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error
+	UpdateUserAuths(username string, auths []rpc.AuthType) error
+	UpdateUserAuthsContext(ctx context.Context, username string, auths []rpc.AuthType) error
+	GetUser(username string) (*User, error)
+	GetUserContext(ctx context.Context, username string) (*User, error)
+	ListUsers() ([]User, error)
+	ListUsersContext(ctx context.Context) ([]User, error)
+	CurrentUserCan(auth rpc.AuthType) (bool, error)
+	CurrentUserCanContext(ctx context.Context, auth rpc.AuthType) (bool, error)
+
+	RegisterUDF(name, filePath, className string, udfType rpc.UDFType) error
+	RegisterUDFContext(ctx context.Context, name, filePath, className string, udfType rpc.UDFType) error
+	DropUDF(name string) error
+	DropUDFContext(ctx context.Context, name string) error
+	ListUDFs() ([]RegisteredTask, error)
+	ListUDFsContext(ctx context.Context) ([]RegisteredTask, error)
+
+	Close() error
+}
@@ -103,6 +103,26 @@ func (c *ClusterInfo) IsUseLocalMetaStorage() bool {
 	return c.localMetaStorageInfo != nil
 }
 
+func (c *ClusterInfo) GetIginxInfos() []IginxInfo {
+	return c.iginxInfo
+}
+
+func (c *ClusterInfo) GetStorageEngineInfos() []StorageEngineInfo {
+	return c.storageEngineInfo
+}
+
+func (c *ClusterInfo) GetMetaStorageInfos() []MetaStorageInfo {
+	return c.metaStorageInfo
+}
+
+// GetLocalMetaStoragePath 返回本地元数据存储的路径，未使用本地元数据存储时返回空字符串
+func (c *ClusterInfo) GetLocalMetaStoragePath() string {
+	if c.localMetaStorageInfo == nil {
+		return ""
+	}
+	return c.localMetaStorageInfo.path
+}
+
 func (i *IginxInfo) ToString() string {
 	return "Id: " + strconv.FormatInt(i.Id, 10) +
 		", Ip: " + i.Ip +
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var ErrNoCurveMatch = errors.New("no matched curve")
+
+// CurveMatchResult 是与查询曲线最相似的序列以及匹配段的起始时间戳
+type CurveMatchResult struct {
+	Path      string
+	Timestamp int64
+}
+
+// CurveMatch 在 [startTime, endTime) 内查找与 curve 最相似的序列片段，unit 为曲线相邻两点的时间间隔。
+// 没有匹配结果时返回 ErrNoCurveMatch
+func (s *Session) CurveMatch(paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error) {
+	return s.CurveMatchContext(context.Background(), paths, startTime, endTime, curve, unit)
+}
+
+func (s *Session) CurveMatchContext(ctx context.Context, paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error) {
+	if err := validateCurveMatch(paths, startTime, endTime, curve, unit); err != nil {
+		return nil, err
+	}
+	req := rpc.CurveMatchReq{
+		SessionId:  s.sessionId,
+		Paths:      s.mergeAndSortPaths(paths),
+		StartTime:  startTime,
+		EndTime:    endTime,
+		CurveQuery: curve,
+		CurveUnit:  unit,
+	}
+
+	var resp *rpc.CurveMatchResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.CurveMatch(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New("curve match resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.IsSetMatchedPath() || resp.GetMatchedPath() == "" {
+		return nil, ErrNoCurveMatch
+	}
+	return &CurveMatchResult{
+		Path:      resp.GetMatchedPath(),
+		Timestamp: resp.GetMatchedTimestamp(),
+	}, nil
+}
+
+func (p *SessionPool) CurveMatch(paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error) {
+	return p.CurveMatchContext(context.Background(), paths, startTime, endTime, curve, unit)
+}
+
+func (p *SessionPool) CurveMatchContext(ctx context.Context, paths []string, startTime, endTime int64, curve []float64, unit int64) (*CurveMatchResult, error) {
+	var ret *CurveMatchResult
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.CurveMatchContext(ctx, paths, startTime, endTime, curve, unit)
+		return err
+	})
+	return ret, err
+}
+
+func validateCurveMatch(paths []string, startTime, endTime int64, curve []float64, unit int64) error {
+	if len(paths) == 0 {
+		return errors.New("paths should not be empty")
+	}
+	if startTime >= endTime {
+		return errors.New("startTime should be less than endTime")
+	}
+	if unit <= 0 {
+		return errors.New("curve unit should be positive")
+	}
+	if len(curve) < 2 {
+		return errors.New("curve should have at least two points")
+	}
+	for i, value := range curve {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("curve[%d] is %v", i, value)
+		}
+	}
+	// 曲线匹配会对曲线做归一化，所有点相同的曲线没有形状可以比较
+	isFlat := true
+	for _, value := range curve[1:] {
+		if value != curve[0] {
+			isFlat = false
+			break
+		}
+	}
+	if isFlat {
+		return errors.New("curve should not be constant")
+	}
+	// 时间范围可能超过 int64，按无符号数计算
+	if uint64(len(curve)-1) > (uint64(endTime)-uint64(startTime))/uint64(unit) {
+		return errors.New("curve is longer than the time range")
+	}
+	return nil
+}
+
+// Curve 将 path 对应的列转换为曲线，同时返回相邻两点的时间间隔，可以直接用于 CurveMatch。
+// 跳过该列为空的行，其余行的时间戳需要等间隔
+func (s *QueryDataSet) Curve(path string) ([]float64, int64, error) {
+	column := -1
+	for i := range s.Paths {
+		if s.Paths[i] == path {
+			column = i
+			break
+		}
+	}
+	if column < 0 {
+		return nil, 0, errors.New("path " + path + " is not in the data set")
+	}
+	if len(s.Timestamps) != len(s.Values) {
+		return nil, 0, errors.New("timestamps and values are not matched")
+	}
+
+	curve := make([]float64, 0, len(s.Values))
+	timestamps := make([]int64, 0, len(s.Values))
+	for i, row := range s.Values {
+		if column >= len(row) || row[column] == nil {
+			continue
+		}
+		value, ok := toFloat64(row[column])
+		if !ok {
+			return nil, 0, fmt.Errorf("value of %s at row %d is not numeric", path, i)
+		}
+		curve = append(curve, value)
+		timestamps = append(timestamps, s.Timestamps[i])
+	}
+
+	var unit int64
+	if len(timestamps) >= 2 {
+		unit = timestamps[1] - timestamps[0]
+		for i := 2; i < len(timestamps); i++ {
+			if timestamps[i]-timestamps[i-1] != unit {
+				return nil, 0, errors.New("timestamps are not evenly spaced")
+			}
+		}
+	}
+	return curve, unit, nil
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/client"
+)
+
+func TestQueryDataSetCurve(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps []int64
+		values     [][]interface{}
+		wantCurve  []float64
+		wantUnit   int64
+		wantErr    bool
+	}{
+		{
+			name:       "evenly spaced",
+			timestamps: []int64{10, 20, 30},
+			values:     [][]interface{}{{int64(1), nil}, {int64(2), "x"}, {int64(3), nil}},
+			wantCurve:  []float64{1, 2, 3},
+			wantUnit:   10,
+		},
+		{
+			// 另一列有值的行在 a.b 中为空，不参与间隔的计算
+			name:       "null rows of other columns",
+			timestamps: []int64{10, 15, 20, 25, 30},
+			values:     [][]interface{}{{1.5, nil}, {nil, "x"}, {2.5, nil}, {nil, "y"}, {3.5, nil}},
+			wantCurve:  []float64{1.5, 2.5, 3.5},
+			wantUnit:   10,
+		},
+		{
+			name:       "not evenly spaced",
+			timestamps: []int64{10, 20, 25, 40},
+			values:     [][]interface{}{{int32(1), nil}, {int32(2), nil}, {nil, "x"}, {int32(3), nil}},
+			wantErr:    true,
+		},
+		{
+			name:       "more values than timestamps",
+			timestamps: []int64{10},
+			values:     [][]interface{}{{int64(1), nil}, {int64(2), nil}},
+			wantErr:    true,
+		},
+		{
+			name:       "not numeric",
+			timestamps: []int64{10, 20},
+			values:     [][]interface{}{{"x", nil}, {"y", nil}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataSet := &client.QueryDataSet{
+				Paths:      []string{"a.b", "a.c"},
+				Timestamps: tt.timestamps,
+				Values:     tt.values,
+			}
+			curve, unit, err := dataSet.Curve("a.b")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Curve() = %v, %d, want an error", curve, unit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(curve, tt.wantCurve) || unit != tt.wantUnit {
+				t.Errorf("Curve() = %v, %d, want %v, %d", curve, unit, tt.wantCurve, tt.wantUnit)
+			}
+		})
+	}
+
+	if _, _, err := (&client.QueryDataSet{Paths: []string{"a.b"}}).Curve("a.c"); err == nil {
+		t.Error("Curve of a missing path should fail")
+	}
+}
@@ -1,14 +1,16 @@
 package client
 
 import (
+	"context"
 	"fmt"
-	"log"
 
 	"github.com/thulab/iginx-client-go/rpc"
 )
 
 type QueryDataSet struct {
-	Paths      []string
+	Paths []string
+	// 每个序列的标签，没有标签时为 nil
+	Tags       []map[string]string
 	Types      []rpc.DataType
 	Timestamps []int64
 	Values     [][]interface{}
@@ -49,7 +51,7 @@ func (s *QueryDataSet) PrintDataSet() {
 		fmt.Print("Time ")
 	}
 	for i := range s.Paths {
-		fmt.Print(s.Paths[i], " ")
+		fmt.Print(s.ColumnName(i), " ")
 	}
 	fmt.Println()
 	for i := range s.Values {
@@ -66,16 +68,21 @@ func (s *QueryDataSet) PrintDataSet() {
 }
 
 type AggregateQueryDataSet struct {
-	Paths         []string
+	Paths []string
+	// 每个序列的标签，没有标签时为 nil
+	Tags          []map[string]string
 	AggregateType rpc.AggregateType
-	Timestamps    []int64
-	Values        []interface{}
+	// 每个聚合结果的数据类型
+	Types      []rpc.DataType
+	Timestamps []int64
+	Values     []interface{}
 }
 
 func NewAggregateQueryDataSet(paths []string, timeBuffer, valuesBuffer []byte, types []rpc.DataType, aggregateType rpc.AggregateType) *AggregateQueryDataSet {
 	dataSet := AggregateQueryDataSet{
 		Paths:         paths,
 		AggregateType: aggregateType,
+		Types:         types,
 		Values:        GetValueByDataTypeList(valuesBuffer, types),
 	}
 
@@ -90,8 +97,8 @@ func (s *AggregateQueryDataSet) PrintDataSet() {
 	fmt.Println("Start print aggregate data set")
 	fmt.Println("-------------------------------------")
 
-	for _, path := range s.Paths {
-		fmt.Print(s.AggregateType.String()+"("+path+")", " ")
+	for i := range s.Paths {
+		fmt.Print(s.AggregateType.String()+"("+s.ColumnName(i)+")", " ")
 	}
 	fmt.Println()
 	for _, value := range s.Values {
@@ -116,11 +123,22 @@ type StreamDataSet struct {
 	fetchSize  int32
 	queryId    int64
 	columns    []string
+	tags       []map[string]string
 	types      []rpc.DataType
 	valuesList [][]byte
 	bitmapList [][]byte
 	index      int
 	state      StateType
+
+	// ctx 为执行查询时的 context，Next 拉取数据时使用
+	ctx       context.Context
+	err       error
+	closed    bool
+	row       []interface{}
+	timestamp int64
+	hasTime   bool
+	// release 在关闭后调用，用于将连接池借出的会话归还
+	release func()
 }
 
 func NewStreamDataSet(session *Session, fetchSize int32, queryId int64, columns []string, types []rpc.DataType, valuesList, bitmapList [][]byte) *StreamDataSet {
@@ -134,14 +152,52 @@ func NewStreamDataSet(session *Session, fetchSize int32, queryId int64, columns
 		bitmapList: bitmapList,
 		index:      0,
 		state:      Unknown,
+		ctx:        context.Background(),
 	}
 }
 
+func (s *StreamDataSet) GetColumns() []string {
+	return s.columns
+}
+
+// GetTags 返回每一列的标签，没有标签时为 nil
+func (s *StreamDataSet) GetTags() []map[string]string {
+	return s.tags
+}
+
+func (s *StreamDataSet) GetDataTypes() []rpc.DataType {
+	return s.types
+}
+
 func (s *StreamDataSet) Close() error {
-	return s.session.closeQuery(s.queryId)
+	return s.CloseContext(context.Background())
 }
 
-func (s *StreamDataSet) fetch() {
+// CloseContext 关闭服务端的查询，重复调用是安全的
+func (s *StreamDataSet) CloseContext(ctx context.Context) error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.bitmapList = nil
+	s.valuesList = nil
+	s.index = 0
+	err := s.session.closeQuery(ctx, s.queryId)
+	if s.release != nil {
+		s.release()
+	}
+	return err
+}
+
+// autoClose 在数据读完或出错时关闭查询，调用方的 context 可能已经取消，因此使用新的 context，关闭失败不影响已读取的结果
+func (s *StreamDataSet) autoClose() {
+	if s.closed {
+		return
+	}
+	_ = s.CloseContext(context.Background())
+}
+
+func (s *StreamDataSet) fetch(ctx context.Context) {
 	if s.index != len(s.bitmapList) { // 只有之前的被消费完才有可能继续取数据
 		return
 	}
@@ -149,10 +205,11 @@ func (s *StreamDataSet) fetch() {
 	s.valuesList = nil
 	s.index = 0
 
-	dataSet, hasMore, err := s.session.fetchResult(s.queryId, s.fetchSize)
+	dataSet, hasMore, err := s.session.fetchResult(ctx, s.queryId, s.fetchSize)
 	if err != nil {
-		log.Printf("fail to fetch stream data, err: %s\n", err)
-		s.state = Unknown
+		s.err = err
+		s.state = NoMore
+		return
 	}
 	if dataSet != nil {
 		s.bitmapList = dataSet.GetBitmapList()
@@ -166,6 +223,14 @@ func (s *StreamDataSet) fetch() {
 }
 
 func (s *StreamDataSet) HasMore() bool {
+	return s.HasMoreContext(context.Background())
+}
+
+// HasMoreContext 判断是否还有数据，拉取数据失败时返回 false，错误通过 Err 获取。数据读完后会自动关闭查询
+func (s *StreamDataSet) HasMoreContext(ctx context.Context) bool {
+	if s.closed || s.err != nil {
+		return false
+	}
 	if s.index < len(s.valuesList) {
 		return true
 	}
@@ -173,13 +238,116 @@ func (s *StreamDataSet) HasMore() bool {
 	s.valuesList = nil
 	s.index = 0
 	if s.state == HasMore || s.state == Unknown {
-		s.fetch()
+		s.fetch(ctx)
+	}
+	if len(s.valuesList) == 0 {
+		s.autoClose()
+		return false
+	}
+	return true
+}
+
+// Next 读取下一行，用法与 sql.Rows 相同：
+//
+//	for dataSet.Next() {
+//		timestamp, values := dataSet.Timestamp(), dataSet.Row()
+//	}
+//	if err := dataSet.Err(); err != nil {
+//	}
+//
+// 使用执行查询时的 context 拉取数据，没有更多数据或出错时返回 false
+func (s *StreamDataSet) Next() bool {
+	return s.NextContext(s.ctx)
+}
+
+func (s *StreamDataSet) NextContext(ctx context.Context) bool {
+	s.row = nil
+	s.timestamp, s.hasTime = 0, false
+	if s.closed || s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		s.autoClose()
+		return false
+	}
+	if !s.HasMoreContext(ctx) {
+		return false
+	}
+
+	values := s.NextRowContext(ctx)
+	if timeColumn := streamTimeColumn(s.columns, s.types); timeColumn >= 0 {
+		s.timestamp, s.hasTime = values[timeColumn].(int64)
+		values = append(values[:timeColumn:timeColumn], values[timeColumn+1:]...)
+	}
+	s.row = values
+	return true
+}
+
+// Row 返回 Next 读取的当前行，结果中有时间戳列时不包含时间戳，与 GetValueColumns 一一对应
+func (s *StreamDataSet) Row() []interface{} {
+	return s.row
+}
+
+// Timestamp 返回当前行的时间戳，结果中没有时间戳列时返回 0
+func (s *StreamDataSet) Timestamp() int64 {
+	return s.timestamp
+}
+
+// HasTimestamp 判断当前行是否有时间戳
+func (s *StreamDataSet) HasTimestamp() bool {
+	return s.hasTime
+}
+
+// GetValueColumns 返回除时间戳列以外的列名
+func (s *StreamDataSet) GetValueColumns() []string {
+	if timeColumn := streamTimeColumn(s.columns, s.types); timeColumn >= 0 {
+		return append(s.columns[:timeColumn:timeColumn], s.columns[timeColumn+1:]...)
 	}
-	return s.valuesList != nil
+	return s.columns
+}
+
+// GetTimeColumn 返回时间戳列的下标，结果中没有时间戳列时返回 -1
+func (s *StreamDataSet) GetTimeColumn() int {
+	return streamTimeColumn(s.columns, s.types)
+}
+
+func (s *StreamDataSet) NextBatch() ([][]byte, [][]byte) {
+	return s.NextBatchContext(s.ctx)
+}
+
+// NextBatchContext 返回一次拉取得到的所有未读取的行，即每行编码后的值和对应的 Bitmap，本地没有未读取的行时先拉取。
+// 没有更多数据或出错时返回 nil，错误通过 Err 获取
+func (s *StreamDataSet) NextBatchContext(ctx context.Context) (valuesList, bitmapList [][]byte) {
+	s.row = nil
+	s.timestamp, s.hasTime = 0, false
+	if s.closed || s.err != nil {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		s.autoClose()
+		return nil, nil
+	}
+	if !s.HasMoreContext(ctx) {
+		return nil, nil
+	}
+	valuesList, bitmapList = s.valuesList[s.index:], s.bitmapList[s.index:]
+	s.index = len(s.bitmapList)
+	return valuesList, bitmapList
+}
+
+// Err 返回迭代过程中拉取数据或 context 的错误，正常读完时返回 nil
+func (s *StreamDataSet) Err() error {
+	return s.err
 }
 
 func (s *StreamDataSet) NextRow() []interface{} {
-	if !s.HasMore() {
+	return s.NextRowContext(context.Background())
+}
+
+func (s *StreamDataSet) NextRowContext(ctx context.Context) []interface{} {
+	if !s.HasMoreContext(ctx) {
 		return nil
 	}
 	// nextRow 只会返回本地的 row，如果本地没有，在进行 hasMore 操作时候，就一定也已经取回来了
@@ -205,8 +373,8 @@ func (s *StreamDataSet) PrintDataSet() {
 	fmt.Println("Start print stream data set")
 	fmt.Println("-------------------------------------")
 
-	for _, column := range s.columns {
-		fmt.Print(column, " ")
+	for i := range s.columns {
+		fmt.Print(s.ColumnName(i), " ")
 	}
 	fmt.Println()
 	for s.HasMore() {
@@ -226,17 +394,38 @@ type SQLDataSet struct {
 	ParseErrorMsg string
 
 	QueryDataSet *QueryDataSet
-
-	TimeSeries  []*TimeSeries
-	ReplicaNum  int32
-	PointsNum   int64
-	ClusterInfo *ClusterInfo
+	// 聚合查询没有逐行数据，结果保存在 AggregateQueryDataSet 中
+	AggregateQueryDataSet *AggregateQueryDataSet
+	Tags                  []map[string]string
+	// 查询语句中的 LIMIT、OFFSET 和 ORDER BY，未指定时 Limit 和 Offset 为 0，Ascending 为 true
+	Limit       int32
+	Offset      int32
+	OrderByPath string
+	Ascending   bool
+
+	TimeSeries      []*TimeSeries
+	ReplicaNum      int32
+	PointsNum       int64
+	ClusterInfo     *ClusterInfo
+	RegisteredTasks []RegisteredTask
+	JobId           int64
+	JobState        rpc.JobState
+	JobIdList       []int64
+
+	aggregateType *rpc.AggregateType
 }
 
 func NewSQLDataSet(resp *rpc.ExecuteSqlResp) *SQLDataSet {
 	dataSet := &SQLDataSet{
 		Type:          resp.GetType(),
 		ParseErrorMsg: resp.GetParseErrorMsg(),
+		Tags:          resp.GetTagsList(),
+		Limit:         resp.GetLimit(),
+		Offset:        resp.GetOffset(),
+		OrderByPath:   resp.GetOrderByPath(),
+		Ascending:     !resp.IsSetAscending() || resp.GetAscending(),
+
+		aggregateType: resp.AggregateType,
 	}
 
 	switch dataSet.Type {
@@ -250,6 +439,9 @@ func NewSQLDataSet(resp *rpc.ExecuteSqlResp) *SQLDataSet {
 		var timeSeries []*TimeSeries
 		for i := 0; i < len(resp.GetPaths()); i++ {
 			ts := NewTimeSeries(resp.GetPaths()[i], resp.GetDataTypeList()[i])
+			if i < len(resp.GetTagsList()) {
+				ts.tags = resp.GetTagsList()[i]
+			}
 			timeSeries = append(timeSeries, &ts)
 		}
 		dataSet.TimeSeries = timeSeries
@@ -263,6 +455,17 @@ func NewSQLDataSet(resp *rpc.ExecuteSqlResp) *SQLDataSet {
 		)
 		break
 	case rpc.SqlType_Query:
+		if !resp.IsSetQueryDataSet() && resp.IsSetValuesList() {
+			dataSet.AggregateQueryDataSet = NewAggregateQueryDataSet(
+				resp.GetPaths(),
+				resp.GetTimestamps(),
+				resp.GetValuesList(),
+				resp.GetDataTypeList(),
+				resp.GetAggregateType(),
+			)
+			dataSet.AggregateQueryDataSet.Tags = resp.GetTagsList()
+			break
+		}
 		dataSet.QueryDataSet = NewQueryDataSet(
 			resp.GetPaths(),
 			resp.GetDataTypeList(),
@@ -270,6 +473,24 @@ func NewSQLDataSet(resp *rpc.ExecuteSqlResp) *SQLDataSet {
 			resp.GetQueryDataSet().GetValuesList(),
 			resp.GetQueryDataSet().GetBitmapList(),
 		)
+		dataSet.QueryDataSet.Tags = resp.GetTagsList()
+		break
+	case rpc.SqlType_ShowRegisterTask:
+		var tasks []RegisteredTask
+		for _, info := range resp.GetRegisterTaskInfos() {
+			tasks = append(tasks, newRegisteredTask(info))
+		}
+		dataSet.RegisteredTasks = tasks
+		break
+	case rpc.SqlType_CommitTransformJob:
+		dataSet.JobId = resp.GetJobId()
+		break
+	case rpc.SqlType_ShowJobStatus:
+		dataSet.JobId = resp.GetJobId()
+		dataSet.JobState = resp.GetJobState()
+		break
+	case rpc.SqlType_ShowEligibleJob:
+		dataSet.JobIdList = resp.GetJobIdList()
 		break
 	}
 
@@ -303,3 +524,51 @@ func (s *SQLDataSet) GetClusterInfo() *ClusterInfo {
 func (s *SQLDataSet) GetQueryDataSet() *QueryDataSet {
 	return s.QueryDataSet
 }
+
+func (s *SQLDataSet) GetAggregateQueryDataSet() *AggregateQueryDataSet {
+	return s.AggregateQueryDataSet
+}
+
+// GetAggregateType 返回聚合查询的聚合类型，不是聚合查询时第二个返回值为 false
+func (s *SQLDataSet) GetAggregateType() (rpc.AggregateType, bool) {
+	if s.aggregateType == nil {
+		return 0, false
+	}
+	return *s.aggregateType, true
+}
+
+func (s *SQLDataSet) GetTags() []map[string]string {
+	return s.Tags
+}
+
+func (s *SQLDataSet) GetLimit() int32 {
+	return s.Limit
+}
+
+func (s *SQLDataSet) GetOffset() int32 {
+	return s.Offset
+}
+
+func (s *SQLDataSet) GetOrderByPath() string {
+	return s.OrderByPath
+}
+
+func (s *SQLDataSet) IsAscending() bool {
+	return s.Ascending
+}
+
+func (s *SQLDataSet) GetRegisteredTasks() []RegisteredTask {
+	return s.RegisteredTasks
+}
+
+func (s *SQLDataSet) GetJobId() int64 {
+	return s.JobId
+}
+
+func (s *SQLDataSet) GetJobState() rpc.JobState {
+	return s.JobState
+}
+
+func (s *SQLDataSet) GetJobIdList() []int64 {
+	return s.JobIdList
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var (
+	ErrAuthFailed     = errors.New("authentication failed")
+	ErrSessionClosed  = errors.New("session is closed")
+	ErrParse          = errors.New("statement parse error")
+	ErrPartialFailure = errors.New("partial failure")
+	ErrPoolClosed     = errors.New("session pool is closed")
+)
+
+// StatusError 是服务端返回非成功状态时的错误，保留了状态码以及失败的子状态
+type StatusError struct {
+	Code    int32
+	Message string
+	// 失败的子状态，例如批量写入中失败的部分
+	SubErrors []*StatusError
+	// 子状态在 rpc.Status.SubStatus 中的下标，顶层错误为 -1
+	Index int
+
+	kind     error
+	subCount int
+}
+
+func NewStatusError(status *rpc.Status) *StatusError {
+	err := &StatusError{
+		Code:    status.GetCode(),
+		Message: status.GetMessage(),
+		Index:   -1,
+
+		subCount: len(status.GetSubStatus()),
+	}
+	for i, subStatus := range status.GetSubStatus() {
+		if subStatus.GetCode() == SuccessCode {
+			continue
+		}
+		subErr := NewStatusError(subStatus)
+		subErr.Index = i
+		err.SubErrors = append(err.SubErrors, subErr)
+	}
+	return err
+}
+
+func (e *StatusError) Error() string {
+	msg := "error occurs: " + e.Message
+	if e.Message == "" {
+		msg += "code " + strconv.Itoa(int(e.Code))
+	}
+	if len(e.SubErrors) == 0 {
+		return msg
+	}
+	var subMessages []string
+	for _, subErr := range e.SubErrors {
+		subMessages = append(subMessages, "["+strconv.Itoa(subErr.Index)+"] "+subErr.Message)
+	}
+	return msg + " (" + strings.Join(subMessages, "; ") + ")"
+}
+
+func (e *StatusError) Is(target error) bool {
+	if target == ErrPartialFailure {
+		return e.IsPartialFailure()
+	}
+	return e.kind != nil && e.kind == target
+}
+
+// IsPartialFailure 判断是否只有部分操作失败，失败的部分记录在 SubErrors 中
+func (e *StatusError) IsPartialFailure() bool {
+	return e.Code == PartialSuccessCode || (len(e.SubErrors) > 0 && len(e.SubErrors) < e.subCount)
+}
+
+func (e *StatusError) withKind(kind error) *StatusError {
+	e.kind = kind
+	return e
+}
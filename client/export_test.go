@@ -0,0 +1,9 @@
+package client
+
+// IsBroken 判断会话是否因连接故障或被中断而等待重连，仅用于测试
+func (s *Session) IsBroken() bool {
+	return s.broken
+}
+
+// NewTransformJobHandle 使用指定的 client 创建作业句柄，仅用于测试
+var NewTransformJobHandle = newTransformJobHandle
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// isConnectionError 判断错误是否由连接故障引起，这类错误发生后连接上的数据流已不可信
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var transportErr thrift.TTransportException
+	var protocolErr thrift.TProtocolException
+	var netErr net.Error
+	return errors.As(err, &transportErr) || errors.As(err, &protocolErr) || errors.As(err, &netErr)
+}
+
+// RetryPolicy 控制幂等调用在连接故障后的重试，每次重试前会切换节点并重新打开会话
+type RetryPolicy struct {
+	// 最大重试次数，为 0 时不重试
+	MaxRetries int
+	// 第一次重试前的等待时间，此后每次翻倍，最多为 MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Backoff:    100 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+func (s *Session) SetRetryPolicy(policy RetryPolicy) {
+	s.retryPolicy = policy
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.Backoff
+	for i := 0; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// markBroken 关闭出错的连接，下一次调用时会自动重新打开会话
+func (s *Session) markBroken() {
+	s.isClose = true
+	s.broken = true
+	if s.transport != nil {
+		_ = s.transport.Close()
+	}
+}
+
+// reconnect 重新建立连接并使用保存的用户名和密码获取新的 sessionId
+func (s *Session) reconnect(ctx context.Context) error {
+	s.markBroken()
+	return s.OpenContext(ctx)
+}
+
+// retryableCall 用于幂等的调用，连接故障时按照重试策略切换到下一个节点重新打开会话并重试。
+// 重新打开后 sessionId 会变化，fn 需要在每次执行时读取 s.sessionId
+func (s *Session) retryableCall(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := s.call(ctx, fn)
+	for retry := 0; retry < s.retryPolicy.MaxRetries && isConnectionError(err) && ctx.Err() == nil; retry++ {
+		timer := time.NewTimer(s.retryPolicy.backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		s.endpointIndex = (s.endpointIndex + 1) % len(s.endpoints)
+		err = s.call(ctx, fn)
+	}
+	return err
+}
+
+// discoverEndpoints 通过集群信息补充其余 IginX 节点，发现失败不影响会话的使用
+func (s *Session) discoverEndpoints(ctx context.Context) {
+	req := rpc.GetClusterInfoReq{
+		SessionId: s.sessionId,
+	}
+
+	var resp *rpc.GetClusterInfoResp
+	err := s.invoke(ctx, func(ctx context.Context) (err error) {
+		resp, err = s.client.GetClusterInfo(ctx, &req)
+		return err
+	})
+	if err != nil || resp == nil || s.verifyStatus(resp.GetStatus()) != nil {
+		return
+	}
+
+	for _, info := range resp.GetIginxInfos() {
+		if ip := net.ParseIP(info.IP); ip != nil && ip.IsUnspecified() {
+			continue
+		}
+		endpoint := net.JoinHostPort(info.IP, strconv.Itoa(int(info.Port)))
+		if !s.hasEndpoint(endpoint) {
+			s.endpoints = append(s.endpoints, endpoint)
+		}
+	}
+}
+
+func (s *Session) hasEndpoint(endpoint string) bool {
+	for _, e := range s.endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
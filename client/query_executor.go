@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+type QueryExecutorConfig struct {
+	// 同时执行的子查询数，为 0 时使用连接池的 MaxSize
+	Parallelism int
+	// 每个子查询最多包含的路径数，为 0 时不按路径拆分
+	PathsPerQuery int
+	// 每个子查询的时间跨度，为 0 时不按时间拆分。降采样查询会向上取整为 precision 的整数倍
+	WindowSize int64
+}
+
+// QueryExecutor 将查询按路径和时间拆分为多个子查询，使用连接池中的多个会话并发执行，再合并为一个按时间对齐的结果
+type QueryExecutor struct {
+	pool   *SessionPool
+	config QueryExecutorConfig
+}
+
+func NewQueryExecutor(pool *SessionPool, config QueryExecutorConfig) *QueryExecutor {
+	if config.Parallelism <= 0 {
+		config.Parallelism = pool.config.MaxSize
+	}
+	return &QueryExecutor{
+		pool:   pool,
+		config: config,
+	}
+}
+
+type subQuery struct {
+	paths     []string
+	startTime int64
+	endTime   int64
+}
+
+func (e *QueryExecutor) Query(paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return e.QueryContext(context.Background(), paths, startTime, endTime, tagList)
+}
+
+func (e *QueryExecutor) QueryContext(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	subQueries, err := e.split(paths, startTime, endTime, e.config.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, subQueries, func(ctx context.Context, session *Session, q subQuery) (*QueryDataSet, error) {
+		return session.QueryContext(ctx, q.paths, q.startTime, q.endTime, tagList)
+	})
+}
+
+func (e *QueryExecutor) DownSampleQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return e.DownSampleQueryContext(context.Background(), paths, startTime, endTime, aggregateType, precision, tagList)
+}
+
+func (e *QueryExecutor) DownSampleQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
+	if precision <= 0 {
+		return nil, errors.New("precision should be positive")
+	}
+	// 窗口对齐到 precision，避免一个降采样区间被拆到两个子查询中
+	windowSize := e.config.WindowSize
+	if windowSize > 0 && windowSize%precision != 0 {
+		windowSize += precision - windowSize%precision
+	}
+	subQueries, err := e.split(paths, startTime, endTime, windowSize)
+	if err != nil {
+		return nil, err
+	}
+	return e.run(ctx, subQueries, func(ctx context.Context, session *Session, q subQuery) (*QueryDataSet, error) {
+		return session.DownSampleQueryContext(ctx, q.paths, q.startTime, q.endTime, aggregateType, precision, tagList)
+	})
+}
+
+func (e *QueryExecutor) split(paths []string, startTime, endTime, windowSize int64) ([]subQuery, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("paths should not be empty")
+	}
+	if startTime >= endTime {
+		return nil, errors.New("startTime should be less than endTime")
+	}
+
+	var pathGroups [][]string
+	if e.config.PathsPerQuery <= 0 {
+		pathGroups = [][]string{paths}
+	} else {
+		for i := 0; i < len(paths); i += e.config.PathsPerQuery {
+			end := i + e.config.PathsPerQuery
+			if end > len(paths) {
+				end = len(paths)
+			}
+			pathGroups = append(pathGroups, paths[i:end])
+		}
+	}
+
+	var ret []subQuery
+	for start := startTime; start < endTime; {
+		end := endTime
+		if windowSize > 0 && start+windowSize > start && start+windowSize < endTime {
+			end = start + windowSize
+		}
+		// 查询接口会对 paths 原地排序，每个子查询使用自己的副本，避免并发修改和改变调用方的切片
+		for _, group := range pathGroups {
+			ret = append(ret, subQuery{paths: append([]string(nil), group...), startTime: start, endTime: end})
+		}
+		start = end
+	}
+	return ret, nil
+}
+
+// run 并发执行子查询，任意一个子查询失败时取消其余的子查询并返回该错误
+func (e *QueryExecutor) run(ctx context.Context, subQueries []subQuery, query func(ctx context.Context, session *Session, q subQuery) (*QueryDataSet, error)) (*QueryDataSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*QueryDataSet, len(subQueries))
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	tokens := make(chan struct{}, e.config.Parallelism)
+	for i := range subQueries {
+		select {
+		case tokens <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-tokens
+				wg.Done()
+			}()
+			err := e.pool.do(ctx, func(session *Session) (err error) {
+				results[i], err = query(ctx, session, subQueries[i])
+				return err
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return MergeQueryDataSets(results...), nil
+}
+
+type mergedColumn struct {
+	path     string
+	tags     map[string]string
+	dataType rpc.DataType
+}
+
+// MergeQueryDataSets 将多个数据集按时间戳对齐合并，同一序列（路径和标签都相同）合并为一列，缺失的值为 nil。
+// 结果中的列按路径和标签排序，时间戳升序
+func MergeQueryDataSets(dataSets ...*QueryDataSet) *QueryDataSet {
+	columnIndex := make(map[string]int)
+	var columns []mergedColumn
+	hasTags := false
+	timestampSet := make(map[int64]struct{})
+	for _, dataSet := range dataSets {
+		if dataSet == nil {
+			continue
+		}
+		for i := range dataSet.Paths {
+			name := dataSet.ColumnName(i)
+			if _, ok := columnIndex[name]; ok {
+				continue
+			}
+			column := mergedColumn{path: dataSet.Paths[i], tags: columnTags(dataSet.Tags, i)}
+			if i < len(dataSet.Types) {
+				column.dataType = dataSet.Types[i]
+			}
+			if len(column.tags) > 0 {
+				hasTags = true
+			}
+			columnIndex[name] = len(columns)
+			columns = append(columns, column)
+		}
+		for _, timestamp := range dataSet.Timestamps {
+			timestampSet[timestamp] = struct{}{}
+		}
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool {
+		if columns[i].path != columns[j].path {
+			return columns[i].path < columns[j].path
+		}
+		return tagsKey(columns[i].tags) < tagsKey(columns[j].tags)
+	})
+	ret := &QueryDataSet{}
+	for i, column := range columns {
+		columnIndex[formatColumn(column.path, column.tags)] = i
+		ret.Paths = append(ret.Paths, column.path)
+		ret.Types = append(ret.Types, column.dataType)
+		if hasTags {
+			ret.Tags = append(ret.Tags, column.tags)
+		}
+	}
+
+	ret.Timestamps = make([]int64, 0, len(timestampSet))
+	for timestamp := range timestampSet {
+		ret.Timestamps = append(ret.Timestamps, timestamp)
+	}
+	sort.Slice(ret.Timestamps, func(i, j int) bool {
+		return ret.Timestamps[i] < ret.Timestamps[j]
+	})
+	rowIndex := make(map[int64]int, len(ret.Timestamps))
+	ret.Values = make([][]interface{}, len(ret.Timestamps))
+	for i, timestamp := range ret.Timestamps {
+		rowIndex[timestamp] = i
+		ret.Values[i] = make([]interface{}, len(columns))
+	}
+
+	for _, dataSet := range dataSets {
+		if dataSet == nil {
+			continue
+		}
+		targets := make([]int, len(dataSet.Paths))
+		for i := range dataSet.Paths {
+			targets[i] = columnIndex[dataSet.ColumnName(i)]
+		}
+		for i, row := range dataSet.Values {
+			if i >= len(dataSet.Timestamps) {
+				break
+			}
+			values := ret.Values[rowIndex[dataSet.Timestamps[i]]]
+			for j, value := range row {
+				// 路径中的通配符可能使同一序列出现在多个子查询中，保留第一个非空值
+				if value != nil && j < len(targets) && values[targets[j]] == nil {
+					values[targets[j]] = value
+				}
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,42 @@
+package client_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func TestQueryExecutorUnsortedPaths(t *testing.T) {
+	server := newServer(t)
+	session := openSession(t, server)
+	var timestamps []int64
+	var rows [][]interface{}
+	for i := int64(0); i < 40; i += 5 {
+		timestamps = append(timestamps, i)
+		rows = append(rows, []interface{}{i, i + 1, i + 2})
+	}
+	types := []rpc.DataType{rpc.DataType_LONG, rpc.DataType_LONG, rpc.DataType_LONG}
+	if err := session.InsertRowRecords([]string{"t.a", "t.b", "t.c"}, timestamps, rows, types, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := newPoolWithConfig(t, server.PoolConfig(), 4)
+	// 每个窗口的子查询并发执行，不能共享同一个 paths 切片
+	executor := client.NewQueryExecutor(pool, client.QueryExecutorConfig{WindowSize: 10})
+	paths := []string{"t.c", "t.a", "t.b"}
+	dataSet, err := executor.Query(paths, 0, 40, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"t.c", "t.a", "t.b"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("caller's paths were reordered to %v", paths)
+	}
+	if want := []string{"t.a", "t.b", "t.c"}; !reflect.DeepEqual(dataSet.Paths, want) {
+		t.Errorf("paths = %v, want %v", dataSet.Paths, want)
+	}
+	if !reflect.DeepEqual(dataSet.Timestamps, timestamps) || !reflect.DeepEqual(dataSet.Values, rows) {
+		t.Errorf("got %v %v, want %v %v", dataSet.Timestamps, dataSet.Values, timestamps, rows)
+	}
+}
@@ -0,0 +1,332 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+type QueryStreamOptions struct {
+	// 每个窗口的时间跨度，为 0 时根据 PointsPerWindow 估算
+	WindowSize int64
+	// 每个窗口期望的数据点数，WindowSize 为 0 时使用，会先进行一次 COUNT 查询估算数据密度
+	PointsPerWindow int64
+	// 后台预取的窗口数，为 0 时在 Next 中顺序查询。预取期间 Session 不能用于其他请求
+	Prefetch int
+	// Precision 大于 0 时进行降采样查询，窗口大小会向上取整为 Precision 的整数倍
+	AggregateType rpc.AggregateType
+	Precision     int64
+}
+
+type queryWindowFunc func(ctx context.Context, startTime, endTime int64) (*QueryDataSet, error)
+
+type windowResult struct {
+	dataSet *QueryDataSet
+	err     error
+}
+
+// QueryStream 将 [startTime, endTime) 按窗口依次查询并逐行返回，内存中最多保留 Prefetch+1 个窗口的数据。
+// 列由第一个有数据的窗口确定，之后的窗口中没有数据的列为 nil，出现新的列时返回错误
+type QueryStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	query  queryWindowFunc
+
+	next       int64
+	endTime    int64
+	windowSize int64
+	results    chan windowResult
+
+	// columns 只包含列的信息，在第一个有数据的窗口确定
+	columns     *QueryDataSet
+	columnIndex map[string]int
+	dataSet     *QueryDataSet
+	index       int
+	err         error
+	closed      bool
+}
+
+func newQueryStream(ctx context.Context, query queryWindowFunc, startTime, endTime, windowSize int64, prefetch int) *QueryStream {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &QueryStream{
+		ctx:        ctx,
+		cancel:     cancel,
+		query:      query,
+		next:       startTime,
+		endTime:    endTime,
+		windowSize: windowSize,
+	}
+	if prefetch > 0 {
+		s.results = make(chan windowResult, prefetch)
+		go s.prefetch()
+	}
+	return s
+}
+
+// nextWindow 返回下一个窗口，最后一个窗口截断到 endTime
+func (s *QueryStream) nextWindow() (int64, int64, bool) {
+	if s.next >= s.endTime {
+		return 0, 0, false
+	}
+	start := s.next
+	end := start + s.windowSize
+	if end > s.endTime || end < start {
+		end = s.endTime
+	}
+	s.next = end
+	return start, end, true
+}
+
+func (s *QueryStream) prefetch() {
+	defer close(s.results)
+	for {
+		start, end, ok := s.nextWindow()
+		if !ok {
+			return
+		}
+		dataSet, err := s.query(s.ctx, start, end)
+		select {
+		case s.results <- windowResult{dataSet: dataSet, err: err}:
+		case <-s.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *QueryStream) fetch() (*QueryDataSet, bool, error) {
+	if s.results != nil {
+		select {
+		case result, ok := <-s.results:
+			if !ok {
+				return nil, false, s.ctx.Err()
+			}
+			return result.dataSet, true, result.err
+		case <-s.ctx.Done():
+			return nil, false, s.ctx.Err()
+		}
+	}
+	if err := s.ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	start, end, ok := s.nextWindow()
+	if !ok {
+		return nil, false, nil
+	}
+	dataSet, err := s.query(s.ctx, start, end)
+	return dataSet, true, err
+}
+
+// Next 读取下一行，没有更多数据或出错时返回 false 并关闭查询，错误通过 Err 获取
+func (s *QueryStream) Next() bool {
+	for {
+		if s.closed || s.err != nil {
+			return false
+		}
+		if s.dataSet != nil && s.index < len(s.dataSet.Values) {
+			s.index++
+			return true
+		}
+		s.dataSet, s.index = nil, 0
+		dataSet, ok, err := s.fetch()
+		if err != nil {
+			s.err = err
+			s.Close()
+			return false
+		}
+		if !ok {
+			s.Close()
+			return false
+		}
+		if s.dataSet, err = s.align(dataSet); err != nil {
+			s.err = err
+			s.Close()
+			return false
+		}
+	}
+}
+
+// align 将窗口的列按第一个有数据的窗口对齐，该窗口没有的列填充为 nil
+func (s *QueryStream) align(dataSet *QueryDataSet) (*QueryDataSet, error) {
+	if dataSet == nil || len(dataSet.Paths) == 0 {
+		return dataSet, nil
+	}
+	if s.columns == nil {
+		s.columns = &QueryDataSet{Paths: dataSet.Paths, Tags: dataSet.Tags, Types: dataSet.Types}
+		s.columnIndex = make(map[string]int, len(dataSet.Paths))
+		for i := range dataSet.Paths {
+			s.columnIndex[dataSet.ColumnName(i)] = i
+		}
+		return dataSet, nil
+	}
+
+	columns := make([]int, len(dataSet.Paths))
+	aligned := len(dataSet.Paths) == len(s.columns.Paths)
+	for i := range dataSet.Paths {
+		name := dataSet.ColumnName(i)
+		column, ok := s.columnIndex[name]
+		if !ok {
+			return nil, errors.New("column " + name + " is not in the first window with data")
+		}
+		columns[i] = column
+		aligned = aligned && column == i
+	}
+	if aligned {
+		return dataSet, nil
+	}
+	values := make([][]interface{}, len(dataSet.Values))
+	for i, row := range dataSet.Values {
+		values[i] = make([]interface{}, len(s.columns.Paths))
+		for j, value := range row {
+			values[i][columns[j]] = value
+		}
+	}
+	return &QueryDataSet{
+		Paths:      s.columns.Paths,
+		Tags:       s.columns.Tags,
+		Types:      s.columns.Types,
+		Timestamps: dataSet.Timestamps,
+		Values:     values,
+	}, nil
+}
+
+// Timestamp 返回当前行的时间戳
+func (s *QueryStream) Timestamp() int64 {
+	if s.dataSet == nil || s.index == 0 || s.index > len(s.dataSet.Timestamps) {
+		return 0
+	}
+	return s.dataSet.Timestamps[s.index-1]
+}
+
+// Row 返回当前行的值，与 Paths 一一对应
+func (s *QueryStream) Row() []interface{} {
+	if s.dataSet == nil || s.index == 0 {
+		return nil
+	}
+	return s.dataSet.Values[s.index-1]
+}
+
+// Paths 返回第一个有数据的窗口中的序列，在此之前返回 nil
+func (s *QueryStream) Paths() []string {
+	if s.columns == nil {
+		return nil
+	}
+	return s.columns.Paths
+}
+
+func (s *QueryStream) Tags() []map[string]string {
+	if s.columns == nil {
+		return nil
+	}
+	return s.columns.Tags
+}
+
+func (s *QueryStream) Types() []rpc.DataType {
+	if s.columns == nil {
+		return nil
+	}
+	return s.columns.Types
+}
+
+// ColumnName 返回带标签的列名
+func (s *QueryStream) ColumnName(index int) string {
+	return s.columns.ColumnName(index)
+}
+
+func (s *QueryStream) Err() error {
+	return s.err
+}
+
+// Close 停止查询并等待预取结束，重复调用是安全的
+func (s *QueryStream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.cancel()
+	if s.results != nil {
+		for range s.results {
+		}
+	}
+	return nil
+}
+
+// QueryStream 按窗口流式查询 [startTime, endTime) 内的数据，适用于结果无法一次放入内存的大范围查询
+func (s *Session) QueryStream(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string, options QueryStreamOptions) (*QueryStream, error) {
+	windowSize, err := queryStreamWindow(ctx, startTime, endTime, options, func(ctx context.Context) (*AggregateQueryDataSet, error) {
+		return s.AggregateQueryContext(ctx, paths, startTime, endTime, rpc.AggregateType_COUNT, tagList)
+	})
+	if err != nil {
+		return nil, err
+	}
+	query := func(ctx context.Context, start, end int64) (*QueryDataSet, error) {
+		if options.Precision > 0 {
+			return s.DownSampleQueryContext(ctx, paths, start, end, options.AggregateType, options.Precision, tagList)
+		}
+		return s.QueryContext(ctx, paths, start, end, tagList)
+	}
+	return newQueryStream(ctx, query, startTime, endTime, windowSize, options.Prefetch), nil
+}
+
+// QueryStream 每个窗口从连接池中取一个会话进行查询
+func (p *SessionPool) QueryStream(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string, options QueryStreamOptions) (*QueryStream, error) {
+	windowSize, err := queryStreamWindow(ctx, startTime, endTime, options, func(ctx context.Context) (*AggregateQueryDataSet, error) {
+		return p.AggregateQueryContext(ctx, paths, startTime, endTime, rpc.AggregateType_COUNT, tagList)
+	})
+	if err != nil {
+		return nil, err
+	}
+	query := func(ctx context.Context, start, end int64) (*QueryDataSet, error) {
+		if options.Precision > 0 {
+			return p.DownSampleQueryContext(ctx, paths, start, end, options.AggregateType, options.Precision, tagList)
+		}
+		return p.QueryContext(ctx, paths, start, end, tagList)
+	}
+	return newQueryStream(ctx, query, startTime, endTime, windowSize, options.Prefetch), nil
+}
+
+func queryStreamWindow(ctx context.Context, startTime, endTime int64, options QueryStreamOptions, count func(ctx context.Context) (*AggregateQueryDataSet, error)) (int64, error) {
+	if startTime >= endTime {
+		return 0, errors.New("startTime should be less than endTime")
+	}
+	if options.WindowSize < 0 || options.PointsPerWindow < 0 || options.Prefetch < 0 || options.Precision < 0 {
+		return 0, errors.New("query stream options should not be negative")
+	}
+	if options.WindowSize == 0 && options.PointsPerWindow == 0 {
+		return 0, errors.New("either WindowSize or PointsPerWindow should be set")
+	}
+
+	// 时间范围可能超过 int64，按无符号数计算
+	span := uint64(endTime) - uint64(startTime)
+	windowSize := uint64(options.WindowSize)
+	if windowSize == 0 {
+		dataSet, err := count(ctx)
+		if err != nil {
+			return 0, err
+		}
+		var total float64
+		for _, value := range dataSet.Values {
+			if v, ok := toFloat64(value); ok {
+				total += v
+			}
+		}
+		windowSize = span
+		if estimated := float64(span) / total * float64(options.PointsPerWindow); total > 0 && estimated < float64(span) {
+			windowSize = uint64(estimated)
+		}
+	}
+	if precision := uint64(options.Precision); precision > 0 && windowSize%precision != 0 {
+		windowSize += precision - windowSize%precision
+	}
+	if windowSize == 0 {
+		windowSize = 1
+	}
+	if windowSize > span || windowSize > 1<<63-1 {
+		// 窗口覆盖整个范围时只查询一次，nextWindow 会截断到 endTime
+		return 1<<63 - 1, nil
+	}
+	return int64(windowSize), nil
+}
@@ -0,0 +1,59 @@
+package client_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func TestQueryStreamColumns(t *testing.T) {
+	session := openSession(t, newServer(t))
+	// a.b 只在第一个窗口中有数据，a.d 只在最后一个窗口中有数据
+	err := session.InsertNonAlignedColumnRecords([]string{"a.b", "a.c", "a.d"}, []int64{1, 2, 11, 21},
+		[][]interface{}{{int64(1), int64(2), nil, nil}, {nil, 2.5, 11.5, nil}, {nil, nil, nil, "x"}},
+		[]rpc.DataType{rpc.DataType_LONG, rpc.DataType_DOUBLE, rpc.DataType_BINARY}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefetch := range []int{0, 2} {
+		stream, err := session.QueryStream(context.Background(), []string{"a.*"}, 0, 20, nil,
+			client.QueryStreamOptions{WindowSize: 10, Prefetch: prefetch})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var timestamps []int64
+		var rows [][]interface{}
+		for stream.Next() {
+			timestamps = append(timestamps, stream.Timestamp())
+			rows = append(rows, stream.Row())
+			if got, want := stream.Paths(), []string{"a.b", "a.c"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("prefetch %d: paths = %v, want %v", prefetch, got, want)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			t.Fatalf("prefetch %d: %v", prefetch, err)
+		}
+		wantRows := [][]interface{}{{int64(1), nil}, {int64(2), 2.5}, {nil, 11.5}}
+		if !reflect.DeepEqual(timestamps, []int64{1, 2, 11}) || !reflect.DeepEqual(rows, wantRows) {
+			t.Errorf("prefetch %d: got %v %v, want [1 2 11] %v", prefetch, timestamps, rows, wantRows)
+		}
+		if got, want := stream.Types(), []rpc.DataType{rpc.DataType_LONG, rpc.DataType_DOUBLE}; !reflect.DeepEqual(got, want) {
+			t.Errorf("prefetch %d: types = %v, want %v", prefetch, got, want)
+		}
+	}
+
+	// a.d 不在第一个有数据的窗口中
+	stream, err := session.QueryStream(context.Background(), []string{"a.*"}, 0, 30, nil, client.QueryStreamOptions{WindowSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for stream.Next() {
+	}
+	if stream.Err() == nil {
+		t.Error("a column missing from the first window should fail the stream")
+	}
+}
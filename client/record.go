@@ -0,0 +1,293 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// Record 表示同一时间戳下的一组数据点，通过 NewRecord(ts).Add("a.b", 1.5).Tag("host", "x") 构造，
+// 标签作用于该记录中的所有数据点
+type Record struct {
+	Timestamp int64
+
+	fields []recordField
+	tags   map[string]string
+	err    error
+}
+
+type recordField struct {
+	path     string
+	value    interface{}
+	dataType rpc.DataType
+}
+
+func NewRecord(timestamp int64) *Record {
+	return &Record{Timestamp: timestamp}
+}
+
+// Add 添加一个数据点，数据类型由值推断：
+// bool 为 BOOLEAN，int32 为 INTEGER，int 和 int64 为 LONG，float32 为 FLOAT，float64 为 DOUBLE，string 和 []byte 为 BINARY
+func (r *Record) Add(path string, value interface{}) *Record {
+	if r.err != nil {
+		return r
+	}
+	dataType, value, err := inferDataType(value)
+	if err != nil {
+		r.err = fmt.Errorf("record(timestamp=%d) path %q: %s", r.Timestamp, path, err)
+		return r
+	}
+	return r.AddWithType(path, value, dataType)
+}
+
+// AddWithType 添加一个指定数据类型的数据点，值的类型需要与数据类型一致
+func (r *Record) AddWithType(path string, value interface{}, dataType rpc.DataType) *Record {
+	if r.err != nil {
+		return r
+	}
+	if path == "" {
+		r.err = fmt.Errorf("record(timestamp=%d): path should not be empty", r.Timestamp)
+		return r
+	}
+	if value == nil {
+		r.err = fmt.Errorf("record(timestamp=%d) path %q: value should not be nil", r.Timestamp, path)
+		return r
+	}
+	if _, err := RowValuesToBytes([]interface{}{value}, []rpc.DataType{dataType}); err != nil {
+		r.err = fmt.Errorf("record(timestamp=%d) path %q: %v(%v) does not match %s", r.Timestamp, path, value, reflect.TypeOf(value), dataType)
+		return r
+	}
+	for _, field := range r.fields {
+		if field.path == path {
+			r.err = fmt.Errorf("record(timestamp=%d): duplicate path %q", r.Timestamp, path)
+			return r
+		}
+	}
+	r.fields = append(r.fields, recordField{path: path, value: value, dataType: dataType})
+	return r
+}
+
+func (r *Record) Tag(key, value string) *Record {
+	if r.tags == nil {
+		r.tags = make(map[string]string)
+	}
+	r.tags[key] = value
+	return r
+}
+
+// Err 返回构造记录时产生的第一个错误
+func (r *Record) Err() error {
+	return r.err
+}
+
+func inferDataType(value interface{}) (rpc.DataType, interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return rpc.DataType_BOOLEAN, v, nil
+	case int32:
+		return rpc.DataType_INTEGER, v, nil
+	case int:
+		return rpc.DataType_LONG, int64(v), nil
+	case int64:
+		return rpc.DataType_LONG, v, nil
+	case float32:
+		return rpc.DataType_FLOAT, v, nil
+	case float64:
+		return rpc.DataType_DOUBLE, v, nil
+	case string:
+		return rpc.DataType_BINARY, v, nil
+	case []byte:
+		return rpc.DataType_BINARY, string(v), nil
+	case nil:
+		return 0, nil, errors.New("value should not be nil")
+	default:
+		return 0, nil, fmt.Errorf("unsupported value type %v", reflect.TypeOf(value))
+	}
+}
+
+// recordBatch 是由一组记录合并得到的插入请求，每个序列由路径和标签唯一确定
+type recordBatch struct {
+	paths        []string
+	timestamps   []int64
+	valueList    [][]interface{}
+	dataTypeList []rpc.DataType
+	tagsList     []map[string]string
+	// 每个时间戳下所有序列都有值时可以使用对齐写入
+	aligned bool
+}
+
+type recordSeries struct {
+	path     string
+	tags     map[string]string
+	tagsKey  string
+	dataType rpc.DataType
+}
+
+func buildRecordBatch(records []*Record) (*recordBatch, error) {
+	if len(records) == 0 {
+		return nil, errors.New("invalid insert request")
+	}
+
+	seriesIndex := make(map[string]int)
+	var series []recordSeries
+	rows := make(map[int64]map[int]interface{})
+	hasTags := false
+	for _, record := range records {
+		if record == nil {
+			return nil, errors.New("record should not be nil")
+		}
+		if record.err != nil {
+			return nil, record.err
+		}
+		if len(record.fields) == 0 {
+			return nil, fmt.Errorf("record(timestamp=%d) has no field", record.Timestamp)
+		}
+		key := tagsKey(record.tags)
+		if len(record.tags) > 0 {
+			hasTags = true
+		}
+		row, ok := rows[record.Timestamp]
+		if !ok {
+			row = make(map[int]interface{})
+			rows[record.Timestamp] = row
+		}
+		for _, field := range record.fields {
+			id := field.path + key
+			index, ok := seriesIndex[id]
+			if !ok {
+				index = len(series)
+				seriesIndex[id] = index
+				series = append(series, recordSeries{path: field.path, tags: record.tags, tagsKey: key, dataType: field.dataType})
+			} else if series[index].dataType != field.dataType {
+				return nil, fmt.Errorf("record(timestamp=%d) path %q: data type %s conflicts with %s", record.Timestamp, field.path, field.dataType, series[index].dataType)
+			}
+			if _, ok := row[index]; ok {
+				return nil, fmt.Errorf("record(timestamp=%d): duplicate value of path %q", record.Timestamp, field.path)
+			}
+			row[index] = field.value
+		}
+	}
+
+	// 插入接口要求序列和时间戳递增，这里预先排好序
+	order := make([]int, len(series))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := series[order[i]], series[order[j]]
+		if a.path != b.path {
+			return a.path < b.path
+		}
+		return a.tagsKey < b.tagsKey
+	})
+	timestamps := make([]int64, 0, len(rows))
+	for timestamp := range rows {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+
+	batch := &recordBatch{
+		timestamps: timestamps,
+		aligned:    true,
+	}
+	for _, index := range order {
+		batch.paths = append(batch.paths, series[index].path)
+		batch.dataTypeList = append(batch.dataTypeList, series[index].dataType)
+		if hasTags {
+			tags := series[index].tags
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			batch.tagsList = append(batch.tagsList, tags)
+		}
+	}
+	for _, timestamp := range timestamps {
+		row := rows[timestamp]
+		if len(row) != len(series) {
+			batch.aligned = false
+		}
+		values := make([]interface{}, len(order))
+		for i, index := range order {
+			values[i] = row[index]
+		}
+		batch.valueList = append(batch.valueList, values)
+	}
+	return batch, nil
+}
+
+// InsertRecords 合并写入一组记录。所有记录在发送前校验，
+// 每个时间戳下所有序列都有值时使用对齐写入，否则使用非对齐写入
+func (s *Session) InsertRecords(records ...*Record) error {
+	return s.InsertRecordsContext(context.Background(), records...)
+}
+
+func (s *Session) InsertRecordsContext(ctx context.Context, records ...*Record) error {
+	batch, err := buildRecordBatch(records)
+	if err != nil {
+		return err
+	}
+	if batch.aligned {
+		return s.InsertRowRecordsContext(ctx, batch.paths, batch.timestamps, batch.valueList, batch.dataTypeList, batch.tagsList)
+	}
+	return s.InsertNonAlignedRowRecordsContext(ctx, batch.paths, batch.timestamps, batch.valueList, batch.dataTypeList, batch.tagsList)
+}
+
+func (p *SessionPool) InsertRecords(records ...*Record) error {
+	return p.InsertRecordsContext(context.Background(), records...)
+}
+
+func (p *SessionPool) InsertRecordsContext(ctx context.Context, records ...*Record) error {
+	batch, err := buildRecordBatch(records)
+	if err != nil {
+		return err
+	}
+	return p.do(ctx, func(session *Session) error {
+		if batch.aligned {
+			return session.InsertRowRecordsContext(ctx, batch.paths, batch.timestamps, batch.valueList, batch.dataTypeList, batch.tagsList)
+		}
+		return session.InsertNonAlignedRowRecordsContext(ctx, batch.paths, batch.timestamps, batch.valueList, batch.dataTypeList, batch.tagsList)
+	})
+}
+
+// WriteRecord 将一条记录加入缓存，记录中的数据点共享该记录的标签
+func (w *BatchWriter) WriteRecord(record *Record) error {
+	return w.WriteRecordContext(context.Background(), record)
+}
+
+func (w *BatchWriter) WriteRecordContext(ctx context.Context, record *Record) error {
+	if record == nil {
+		return errors.New("record should not be nil")
+	}
+	if record.err != nil {
+		return record.err
+	}
+	if len(record.fields) == 0 {
+		return fmt.Errorf("record(timestamp=%d) has no field", record.Timestamp)
+	}
+	paths := make([]string, len(record.fields))
+	values := make([]interface{}, len(record.fields))
+	dataTypeList := make([]rpc.DataType, len(record.fields))
+	var tagsList []map[string]string
+	var tags map[string]string
+	if len(record.tags) > 0 {
+		tags = make(map[string]string, len(record.tags))
+		for k, v := range record.tags {
+			tags[k] = v
+		}
+	}
+	for i, field := range record.fields {
+		paths[i] = field.path
+		values[i] = field.value
+		dataTypeList[i] = field.dataType
+		if tags != nil {
+			tagsList = append(tagsList, tags)
+		}
+	}
+	return w.WriteRowContext(ctx, paths, record.Timestamp, values, dataTypeList, tagsList)
+}
@@ -17,66 +17,117 @@ const (
 	DefaultUsername = "root"
 	DefaultPassword = "root"
 
-	SuccessCode = 200
+	SuccessCode                 = 200
+	PartialSuccessCode          = 204
+	StatementExecutionErrorCode = 400
+	StatementParseErrorCode     = 401
+	SystemErrorCode             = 500
 )
 
 type Session struct {
-	host     string
-	port     string
-	username string
-	password string
+	endpoints     []string
+	endpointIndex int
+	discover      bool
+	username      string
+	password      string
 
 	isClose   bool
 	client    *rpc.IServiceClient
 	sessionId int64
 	transport thrift.TTransport
+
+	// 连接因故障而关闭，下一次调用时会自动重连
+	broken      bool
+	retryPolicy RetryPolicy
+	options     sessionOptions
 }
 
 func NewSession(host, port, username, password string) *Session {
 	return &Session{
-		host:      host,
-		port:      port,
+		endpoints: []string{net.JoinHostPort(host, port)},
 		username:  username,
 		password:  password,
 		isClose:   true,
 		client:    nil,
 		sessionId: 0,
 		transport: nil,
+
+		retryPolicy: DefaultRetryPolicy,
 	}
 }
 
 func NewSessionWithDefaultUser(host, port string) *Session {
+	return NewSession(host, port, DefaultUsername, DefaultPassword)
+}
+
+// NewSessionWithEndpoints 创建一个可以在多个 IginX 节点间故障转移的会话，
+// endpoints 为 host:port 形式的种子节点，打开会话后会通过集群信息发现其余节点
+func NewSessionWithEndpoints(endpoints []string, username, password string, opts ...SessionOption) *Session {
+	opts = append([]SessionOption{WithUser(username, password)}, opts...)
+	return newSessionWithOptions(endpoints, true, opts)
+}
+
+func NewSessionWithOptions(host, port string, opts ...SessionOption) *Session {
+	return newSessionWithOptions([]string{net.JoinHostPort(host, port)}, false, opts)
+}
+
+func newSessionWithOptions(endpoints []string, discover bool, opts []SessionOption) *Session {
+	options := newSessionOptions(opts)
 	return &Session{
-		host:      host,
-		port:      port,
-		username:  DefaultUsername,
-		password:  DefaultPassword,
+		endpoints: append(append([]string(nil), endpoints...), options.endpoints...),
+		discover:  discover || len(options.endpoints) > 0,
+		username:  options.username,
+		password:  options.password,
 		isClose:   true,
-		client:    nil,
-		sessionId: 0,
-		transport: nil,
+
+		retryPolicy: options.retryPolicy,
+		options:     options,
 	}
 }
 
 func (s *Session) Open() error {
+	return s.OpenContext(context.Background())
+}
+
+func (s *Session) OpenContext(ctx context.Context) error {
 	if !s.isClose {
 		return nil
 	}
+	if len(s.endpoints) == 0 {
+		return errors.New("no endpoint to connect")
+	}
 
+	// 从当前节点开始依次尝试每个节点，只有连接失败时才尝试下一个
 	var err error
-	s.transport, err = thrift.NewTSocket(net.JoinHostPort(s.host, s.port))
+	for i := 0; i < len(s.endpoints); i++ {
+		index := (s.endpointIndex + i) % len(s.endpoints)
+		err = s.openEndpoint(ctx, s.endpoints[index])
+		if err == nil {
+			s.endpointIndex = index
+			break
+		}
+		if ctx.Err() != nil || !isConnectionError(err) {
+			return err
+		}
+	}
 	if err != nil {
 		return err
 	}
 
-	if !s.transport.IsOpen() {
-		err = s.transport.Open()
-		if err != nil {
-			return err
-		}
+	if s.discover {
+		s.discoverEndpoints(ctx)
+	}
+	return nil
+}
+
+func (s *Session) openEndpoint(ctx context.Context, endpoint string) error {
+	var err error
+	s.transport, err = s.options.openTransport(ctx, endpoint)
+	if err != nil {
+		return err
 	}
 
-	protocolFactory := thrift.NewTBinaryProtocolFactoryDefault()
+	protocolFactory := s.options.protocolFactory()
 	iprot := protocolFactory.GetProtocol(s.transport)
 	oprot := protocolFactory.GetProtocol(s.transport)
 	s.client = rpc.NewIServiceClient(thrift.NewTStandardClient(iprot, oprot))
@@ -86,26 +137,40 @@ func (s *Session) Open() error {
 		Password: &s.password,
 	}
 
-	resp, err := s.client.OpenSession(context.Background(), &req)
-	if err != nil {
+	var resp *rpc.OpenSessionResp
+	err = s.invoke(ctx, func(ctx context.Context) (err error) {
+		resp, err = s.client.OpenSession(ctx, &req)
 		return err
-	} else if resp == nil {
-		return errors.New("open session resp is nil")
+	})
+	if err == nil && resp == nil {
+		err = errors.New("open session resp is nil")
+	}
+	if err == nil && resp.GetStatus().GetCode() != SuccessCode {
+		err = NewStatusError(resp.GetStatus()).withKind(ErrAuthFailed)
 	}
-
-	err = s.verifyStatus(resp.GetStatus())
 	if err != nil {
+		_ = s.transport.Close()
 		return err
 	}
 
 	s.sessionId = resp.GetSessionId()
 	s.isClose = false
+	s.broken = false
 
 	return nil
 }
 
+func (s *Session) GetEndpoints() []string {
+	return append([]string(nil), s.endpoints...)
+}
+
 func (s *Session) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+func (s *Session) CloseContext(ctx context.Context) error {
 	if s.isClose {
+		s.broken = false
 		return nil
 	}
 
@@ -120,7 +185,11 @@ func (s *Session) Close() error {
 		}
 	}()
 
-	status, err := s.client.CloseSession(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		status, err = s.client.CloseSession(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -134,6 +203,10 @@ func (s *Session) Close() error {
 }
 
 func (s *Session) AddStorageEngine(ip, port, engineType string, extra map[string]string) error {
+	return s.AddStorageEngineContext(context.Background(), ip, port, engineType, extra)
+}
+
+func (s *Session) AddStorageEngineContext(ctx context.Context, ip, port, engineType string, extra map[string]string) error {
 	portInt32, err := strconv.ParseInt(port, 10, 32)
 	if err != nil {
 		return err
@@ -147,16 +220,25 @@ func (s *Session) AddStorageEngine(ip, port, engineType string, extra map[string
 	}
 	engines := []*rpc.StorageEngine{&engine}
 
-	return s.BatchAddStorageEngine(engines)
+	return s.BatchAddStorageEngineContext(ctx, engines)
 }
 
 func (s *Session) BatchAddStorageEngine(engines []*rpc.StorageEngine) error {
+	return s.BatchAddStorageEngineContext(context.Background(), engines)
+}
+
+func (s *Session) BatchAddStorageEngineContext(ctx context.Context, engines []*rpc.StorageEngine) error {
 	req := rpc.AddStorageEnginesReq{
 		SessionId:      s.sessionId,
 		StorageEngines: engines,
 	}
 
-	status, err := s.client.AddStorageEngines(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.AddStorageEngines(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -170,11 +252,20 @@ func (s *Session) BatchAddStorageEngine(engines []*rpc.StorageEngine) error {
 }
 
 func (s *Session) GetReplicaNum() (int32, error) {
+	return s.GetReplicaNumContext(context.Background())
+}
+
+func (s *Session) GetReplicaNumContext(ctx context.Context) (int32, error) {
 	req := rpc.GetReplicaNumReq{
 		SessionId: s.sessionId,
 	}
 
-	resp, err := s.client.GetReplicaNum(context.Background(), &req)
+	var resp *rpc.GetReplicaNumResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.GetReplicaNum(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return 0, err
 	} else if resp == nil {
@@ -190,11 +281,20 @@ func (s *Session) GetReplicaNum() (int32, error) {
 }
 
 func (s *Session) GetClusterInfo() (*ClusterInfo, error) {
+	return s.GetClusterInfoContext(context.Background())
+}
+
+func (s *Session) GetClusterInfoContext(ctx context.Context) (*ClusterInfo, error) {
 	req := rpc.GetClusterInfoReq{
 		SessionId: s.sessionId,
 	}
 
-	resp, err := s.client.GetClusterInfo(context.Background(), &req)
+	var resp *rpc.GetClusterInfoResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.GetClusterInfo(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -211,6 +311,10 @@ func (s *Session) GetClusterInfo() (*ClusterInfo, error) {
 }
 
 func (s *Session) AddUser(username, password string, auths []rpc.AuthType) error {
+	return s.AddUserContext(context.Background(), username, password, auths)
+}
+
+func (s *Session) AddUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error {
 	req := rpc.AddUserReq{
 		SessionId: s.sessionId,
 		Username:  username,
@@ -218,7 +322,12 @@ func (s *Session) AddUser(username, password string, auths []rpc.AuthType) error
 		Auths:     auths,
 	}
 
-	status, err := s.client.AddUser(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.AddUser(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -232,12 +341,21 @@ func (s *Session) AddUser(username, password string, auths []rpc.AuthType) error
 }
 
 func (s *Session) DeleteUser(username string) error {
+	return s.DeleteUserContext(context.Background(), username)
+}
+
+func (s *Session) DeleteUserContext(ctx context.Context, username string) error {
 	req := rpc.DeleteUserReq{
 		SessionId: s.sessionId,
 		Username:  username,
 	}
 
-	status, err := s.client.DeleteUser(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.DeleteUser(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -251,14 +369,36 @@ func (s *Session) DeleteUser(username string) error {
 }
 
 func (s *Session) UpdateUser(username, password string, auths []rpc.AuthType) error {
+	return s.UpdateUserContext(context.Background(), username, password, auths)
+}
+
+func (s *Session) UpdateUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error {
+	return s.updateUser(ctx, username, &password, auths)
+}
+
+// UpdateUserAuths 只修改用户的权限，不修改密码
+func (s *Session) UpdateUserAuths(username string, auths []rpc.AuthType) error {
+	return s.UpdateUserAuthsContext(context.Background(), username, auths)
+}
+
+func (s *Session) UpdateUserAuthsContext(ctx context.Context, username string, auths []rpc.AuthType) error {
+	return s.updateUser(ctx, username, nil, auths)
+}
+
+func (s *Session) updateUser(ctx context.Context, username string, password *string, auths []rpc.AuthType) error {
 	req := rpc.UpdateUserReq{
 		SessionId: s.sessionId,
 		Username:  username,
-		Password:  &password,
+		Password:  password,
 		Auths:     auths,
 	}
 
-	status, err := s.client.UpdateUser(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.UpdateUser(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -272,11 +412,20 @@ func (s *Session) UpdateUser(username, password string, auths []rpc.AuthType) er
 }
 
 func (s *Session) ListTimeSeries() ([]TimeSeries, error) {
+	return s.ListTimeSeriesContext(context.Background())
+}
+
+func (s *Session) ListTimeSeriesContext(ctx context.Context) ([]TimeSeries, error) {
 	req := rpc.ShowColumnsReq{
 		SessionId: s.sessionId,
 	}
 
-	resp, err := s.client.ShowColumns(context.Background(), &req)
+	var resp *rpc.ShowColumnsResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.ShowColumns(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -291,23 +440,39 @@ func (s *Session) ListTimeSeries() ([]TimeSeries, error) {
 	var ret []TimeSeries
 	for i := 0; i < len(resp.GetPaths()); i++ {
 		ts := NewTimeSeries(resp.GetPaths()[i], resp.GetDataTypeList()[i])
+		if i < len(resp.GetTagsList()) {
+			ts.tags = resp.GetTagsList()[i]
+		}
 		ret = append(ret, ts)
 	}
 	return ret, nil
 }
 
 func (s *Session) DeleteTimeSeries(path string) error {
+	return s.DeleteTimeSeriesContext(context.Background(), path)
+}
+
+func (s *Session) DeleteTimeSeriesContext(ctx context.Context, path string) error {
 	paths := []string{path}
-	return s.BatchDeleteTimeSeries(paths)
+	return s.BatchDeleteTimeSeriesContext(ctx, paths)
 }
 
 func (s *Session) BatchDeleteTimeSeries(paths []string) error {
+	return s.BatchDeleteTimeSeriesContext(context.Background(), paths)
+}
+
+func (s *Session) BatchDeleteTimeSeriesContext(ctx context.Context, paths []string) error {
 	req := rpc.DeleteColumnsReq{
 		SessionId: s.sessionId,
 		Paths:     paths,
 	}
 
-	status, err := s.client.DeleteColumns(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.DeleteColumns(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -321,6 +486,10 @@ func (s *Session) BatchDeleteTimeSeries(paths []string) error {
 }
 
 func (s *Session) InsertRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return s.InsertRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (s *Session) InsertRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
 	if paths == nil || timestamps == nil || valueList == nil || dataTypeList == nil ||
 		len(paths) == 0 || len(timestamps) == 0 || len(valueList) == 0 || len(dataTypeList) == 0 {
 		return errors.New("invalid insert request")
@@ -414,7 +583,12 @@ func (s *Session) InsertRowRecords(paths []string, timestamps []int64, valueList
 		TagsList:     sortedTagsList,
 	}
 
-	status, err := s.client.InsertRowRecords(context.Background(), &req)
+	var status *rpc.Status
+	err = s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.InsertRowRecords(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -428,6 +602,10 @@ func (s *Session) InsertRowRecords(paths []string, timestamps []int64, valueList
 }
 
 func (s *Session) InsertNonAlignedRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return s.InsertNonAlignedRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (s *Session) InsertNonAlignedRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
 	if paths == nil || timestamps == nil || valueList == nil || dataTypeList == nil ||
 		len(paths) == 0 || len(timestamps) == 0 || len(valueList) == 0 || len(dataTypeList) == 0 {
 		return errors.New("invalid insert request")
@@ -521,7 +699,12 @@ func (s *Session) InsertNonAlignedRowRecords(paths []string, timestamps []int64,
 		TagsList:     sortedTagsList,
 	}
 
-	status, err := s.client.InsertNonAlignedRowRecords(context.Background(), &req)
+	var status *rpc.Status
+	err = s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.InsertNonAlignedRowRecords(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -535,6 +718,10 @@ func (s *Session) InsertNonAlignedRowRecords(paths []string, timestamps []int64,
 }
 
 func (s *Session) InsertColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return s.InsertColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (s *Session) InsertColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
 	if paths == nil || timestamps == nil || valueList == nil || dataTypeList == nil ||
 		len(paths) == 0 || len(timestamps) == 0 || len(valueList) == 0 || len(dataTypeList) == 0 {
 		return errors.New("invalid insert request")
@@ -625,7 +812,12 @@ func (s *Session) InsertColumnRecords(paths []string, timestamps []int64, valueL
 		TagsList:     sortedTagsList,
 	}
 
-	status, err := s.client.InsertColumnRecords(context.Background(), &req)
+	var status *rpc.Status
+	err = s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.InsertColumnRecords(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -639,6 +831,10 @@ func (s *Session) InsertColumnRecords(paths []string, timestamps []int64, valueL
 }
 
 func (s *Session) InsertNonAlignedColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return s.InsertNonAlignedColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (s *Session) InsertNonAlignedColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
 	if paths == nil || timestamps == nil || valueList == nil || dataTypeList == nil ||
 		len(paths) == 0 || len(timestamps) == 0 || len(valueList) == 0 || len(dataTypeList) == 0 {
 		return errors.New("invalid insert request")
@@ -729,7 +925,12 @@ func (s *Session) InsertNonAlignedColumnRecords(paths []string, timestamps []int
 		TagsList:     sortedTagsList,
 	}
 
-	status, err := s.client.InsertNonAlignedColumnRecords(context.Background(), &req)
+	var status *rpc.Status
+	err = s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.InsertNonAlignedColumnRecords(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -743,11 +944,19 @@ func (s *Session) InsertNonAlignedColumnRecords(paths []string, timestamps []int
 }
 
 func (s *Session) DeleteData(path string, startTime, endTime int64, tagsList map[string][]string) error {
+	return s.DeleteDataContext(context.Background(), path, startTime, endTime, tagsList)
+}
+
+func (s *Session) DeleteDataContext(ctx context.Context, path string, startTime, endTime int64, tagsList map[string][]string) error {
 	paths := []string{path}
-	return s.BatchDeleteData(paths, startTime, endTime, tagsList)
+	return s.BatchDeleteDataContext(ctx, paths, startTime, endTime, tagsList)
 }
 
 func (s *Session) BatchDeleteData(paths []string, startTime, endTime int64, tagsList map[string][]string) error {
+	return s.BatchDeleteDataContext(context.Background(), paths, startTime, endTime, tagsList)
+}
+
+func (s *Session) BatchDeleteDataContext(ctx context.Context, paths []string, startTime, endTime int64, tagsList map[string][]string) error {
 	req := rpc.DeleteDataInColumnsReq{
 		SessionId: s.sessionId,
 		Paths:     paths,
@@ -756,7 +965,12 @@ func (s *Session) BatchDeleteData(paths []string, startTime, endTime int64, tags
 		TagsList:  tagsList,
 	}
 
-	status, err := s.client.DeleteDataInColumns(context.Background(), &req)
+	var status *rpc.Status
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.DeleteDataInColumns(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -770,6 +984,10 @@ func (s *Session) BatchDeleteData(paths []string, startTime, endTime int64, tags
 }
 
 func (s *Session) Query(paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return s.QueryContext(context.Background(), paths, startTime, endTime, tagList)
+}
+
+func (s *Session) QueryContext(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
 	req := rpc.QueryDataReq{
 		SessionId: s.sessionId,
 		Paths:     s.mergeAndSortPaths(paths),
@@ -778,7 +996,12 @@ func (s *Session) Query(paths []string, startTime, endTime int64, tagList map[st
 		TagsList:  tagList,
 	}
 
-	resp, err := s.client.QueryData(context.Background(), &req)
+	var resp *rpc.QueryDataResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.QueryData(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -798,10 +1021,15 @@ func (s *Session) Query(paths []string, startTime, endTime int64, tagList map[st
 		rawDataSet.GetValuesList(),
 		rawDataSet.GetBitmapList(),
 	)
+	ret.Tags = resp.GetTagsList()
 	return ret, nil
 }
 
 func (s *Session) DownSampleQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return s.DownSampleQueryContext(context.Background(), paths, startTime, endTime, aggregateType, precision, tagList)
+}
+
+func (s *Session) DownSampleQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
 	req := rpc.DownsampleQueryReq{
 		SessionId:     s.sessionId,
 		Paths:         s.mergeAndSortPaths(paths),
@@ -812,7 +1040,12 @@ func (s *Session) DownSampleQuery(paths []string, startTime, endTime int64, aggr
 		TagsList:      tagList,
 	}
 
-	resp, err := s.client.DownsampleQuery(context.Background(), &req)
+	var resp *rpc.DownsampleQueryResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.DownsampleQuery(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -832,10 +1065,15 @@ func (s *Session) DownSampleQuery(paths []string, startTime, endTime int64, aggr
 		rawDataSet.GetValuesList(),
 		rawDataSet.GetBitmapList(),
 	)
+	ret.Tags = resp.GetTagsList()
 	return ret, nil
 }
 
 func (s *Session) AggregateQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error) {
+	return s.AggregateQueryContext(context.Background(), paths, startTime, endTime, aggregateType, tagList)
+}
+
+func (s *Session) AggregateQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error) {
 	req := rpc.AggregateQueryReq{
 		SessionId:     s.sessionId,
 		Paths:         s.mergeAndSortPaths(paths),
@@ -845,7 +1083,12 @@ func (s *Session) AggregateQuery(paths []string, startTime, endTime int64, aggre
 		TagsList:      tagList,
 	}
 
-	resp, err := s.client.AggregateQuery(context.Background(), &req)
+	var resp *rpc.AggregateQueryResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.AggregateQuery(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -864,10 +1107,15 @@ func (s *Session) AggregateQuery(paths []string, startTime, endTime int64, aggre
 		resp.GetDataTypeList(),
 		aggregateType,
 	)
+	ret.Tags = resp.GetTagsList()
 	return ret, nil
 }
 
 func (s *Session) LastQuery(paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return s.LastQueryContext(context.Background(), paths, startTime, tagList)
+}
+
+func (s *Session) LastQueryContext(ctx context.Context, paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error) {
 	req := rpc.LastQueryReq{
 		SessionId: s.sessionId,
 		Paths:     s.mergeAndSortPaths(paths),
@@ -875,7 +1123,12 @@ func (s *Session) LastQuery(paths []string, startTime int64, tagList map[string]
 		TagsList:  tagList,
 	}
 
-	resp, err := s.client.LastQuery(context.Background(), &req)
+	var resp *rpc.LastQueryResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.LastQuery(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -895,42 +1148,71 @@ func (s *Session) LastQuery(paths []string, startTime int64, tagList map[string]
 		rawDataSet.GetValuesList(),
 		rawDataSet.GetBitmapList(),
 	)
+	ret.Tags = resp.GetTagsList()
 	return ret, nil
 }
 
 func (s *Session) ExecuteSQL(sql string) (*SQLDataSet, error) {
+	return s.ExecuteSQLContext(context.Background(), sql)
+}
+
+func (s *Session) ExecuteSQLContext(ctx context.Context, sql string) (*SQLDataSet, error) {
 	req := rpc.ExecuteSqlReq{
 		SessionId: s.sessionId,
 		Statement: sql,
 	}
 
-	resp, err := s.client.ExecuteSql(context.Background(), &req)
+	var resp *rpc.ExecuteSqlResp
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.ExecuteSql(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
 		return nil, errors.New("execute SQL resp is nil")
 	}
 
-	err = s.verifyStatus(resp.GetStatus())
-	if err != nil {
-		return nil, err
+	if resp.GetStatus().GetCode() != SuccessCode {
+		statusErr := NewStatusError(resp.GetStatus())
+		if resp.IsSetParseErrorMsg() || statusErr.Code == StatementParseErrorCode {
+			statusErr.withKind(ErrParse)
+			if statusErr.Message == "" {
+				statusErr.Message = resp.GetParseErrorMsg()
+			}
+		}
+		return nil, statusErr
 	}
 
-	return NewSQLDataSet(resp), err
+	return NewSQLDataSet(resp), nil
 }
 
 func (s *Session) ExecuteQuery(statement string) (*StreamDataSet, error) {
-	return s.ExecuteQueryWithFetchSize(statement, math.MaxInt32)
+	return s.ExecuteQueryContext(context.Background(), statement)
+}
+
+func (s *Session) ExecuteQueryContext(ctx context.Context, statement string) (*StreamDataSet, error) {
+	return s.ExecuteQueryWithFetchSizeContext(ctx, statement, math.MaxInt32)
 }
 
 func (s *Session) ExecuteQueryWithFetchSize(statement string, fetchSize int32) (*StreamDataSet, error) {
+	return s.ExecuteQueryWithFetchSizeContext(context.Background(), statement, fetchSize)
+}
+
+func (s *Session) ExecuteQueryWithFetchSizeContext(ctx context.Context, statement string, fetchSize int32) (*StreamDataSet, error) {
 	req := rpc.ExecuteStatementReq{
 		SessionId: s.sessionId,
 		Statement: statement,
 		FetchSize: &fetchSize,
 	}
 
-	resp, err := s.client.ExecuteStatement(context.Background(), &req)
+	var resp *rpc.ExecuteStatementResp
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.ExecuteStatement(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	} else if resp == nil {
@@ -951,18 +1233,25 @@ func (s *Session) ExecuteQueryWithFetchSize(statement string, fetchSize int32) (
 		resp.GetQueryDataSet().GetValuesList(),
 		resp.GetQueryDataSet().GetBitmapList(),
 	)
+	ret.tags = resp.GetTagsList()
+	ret.ctx = ctx
 
 	return ret, nil
 }
 
-func (s *Session) fetchResult(queryId int64, fetchSize int32) (*rpc.QueryDataSetV2, bool, error) {
+func (s *Session) fetchResult(ctx context.Context, queryId int64, fetchSize int32) (*rpc.QueryDataSetV2, bool, error) {
 	req := rpc.FetchResultsReq{
 		SessionId: s.sessionId,
 		QueryId:   queryId,
 		FetchSize: &fetchSize,
 	}
 
-	resp, err := s.client.FetchResults(context.Background(), &req)
+	var resp *rpc.FetchResultsResp
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.FetchResults(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return nil, false, err
 	} else if resp == nil {
@@ -977,13 +1266,18 @@ func (s *Session) fetchResult(queryId int64, fetchSize int32) (*rpc.QueryDataSet
 	return resp.GetQueryDataSet(), resp.GetHasMoreResults(), nil
 }
 
-func (s *Session) closeQuery(queryId int64) error {
+func (s *Session) closeQuery(ctx context.Context, queryId int64) error {
 	req := rpc.CloseStatementReq{
 		SessionId: s.sessionId,
 		QueryId:   queryId,
 	}
 
-	status, err := s.client.CloseStatement(context.Background(), &req)
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.CloseStatement(ctx, &req)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -996,9 +1290,68 @@ func (s *Session) closeQuery(queryId int64) error {
 	return nil
 }
 
+// isValid 检查会话是否已打开且底层连接仍然可用
+func (s *Session) isValid() bool {
+	return !s.isClose && s.transport != nil && s.transport.IsOpen()
+}
+
+// call 执行一次 rpc 调用，会话因连接故障关闭后会先自动重连
+func (s *Session) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.isClose && !s.broken {
+		return ErrSessionClosed
+	}
+	if s.broken {
+		if err := s.reconnect(ctx); err != nil {
+			return err
+		}
+	}
+	return s.invoke(ctx, fn)
+}
+
+// invoke 在当前连接上执行 fn，ctx 被取消时会关闭底层连接以中断阻塞中的读写。
+// 被中断或连接出错后会话被标记为故障，下一次调用时会重新打开
+func (s *Session) invoke(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	if ctx.Done() == nil {
+		err = fn(ctx)
+	} else {
+		done := make(chan struct{})
+		stopped := make(chan bool)
+		go func() {
+			select {
+			case <-ctx.Done():
+				_ = s.transport.Close()
+				stopped <- true
+			case <-done:
+				stopped <- false
+			}
+		}()
+
+		err = fn(ctx)
+		close(done)
+		if interrupted := <-stopped; interrupted {
+			// 连接已被关闭，但 fn 可能在关闭之前已经完成，此时结果仍然有效
+			s.markBroken()
+			if err == nil {
+				return nil
+			}
+			return ctx.Err()
+		}
+	}
+
+	if isConnectionError(err) {
+		s.markBroken()
+	}
+	return err
+}
+
 func (s *Session) verifyStatus(status *rpc.Status) error {
 	if status.GetCode() != SuccessCode {
-		return errors.New("error occurs: " + status.GetMessage())
+		return NewStatusError(status)
 	}
 	return nil
 }
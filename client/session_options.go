@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+type transportType int
+
+const (
+	socketTransport transportType = iota
+	framedTransport
+	bufferedTransport
+)
+
+const (
+	DefaultBufferSize = 4096
+)
+
+type SessionOption func(*sessionOptions)
+
+type sessionOptions struct {
+	username    string
+	password    string
+	endpoints   []string
+	retryPolicy RetryPolicy
+
+	connectTimeout time.Duration
+	socketTimeout  time.Duration
+	tlsConfig      *tls.Config
+	transport      transportType
+	bufferSize     int
+	compact        bool
+
+	// 选项本身的错误，例如证书加载失败，在打开会话时返回
+	err error
+}
+
+func WithUser(username, password string) SessionOption {
+	return func(o *sessionOptions) {
+		o.username = username
+		o.password = password
+	}
+}
+
+// WithEndpoints 添加额外的种子节点并开启节点发现，会话可以在这些节点间故障转移
+func WithEndpoints(endpoints ...string) SessionOption {
+	return func(o *sessionOptions) {
+		o.endpoints = append(o.endpoints, endpoints...)
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) SessionOption {
+	return func(o *sessionOptions) {
+		o.retryPolicy = policy
+	}
+}
+
+func WithConnectTimeout(timeout time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.connectTimeout = timeout
+	}
+}
+
+// WithSocketTimeout 设置每次读写的超时时间，为 0 时不超时
+func WithSocketTimeout(timeout time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.socketTimeout = timeout
+	}
+}
+
+func WithTLSConfig(config *tls.Config) SessionOption {
+	return func(o *sessionOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithTLSFiles 使用 PEM 格式的 CA 证书和客户端证书开启 TLS，
+// caFile 为空时使用系统 CA，certFile 和 keyFile 为空时不发送客户端证书
+func WithTLSFiles(caFile, certFile, keyFile string) SessionOption {
+	return func(o *sessionOptions) {
+		config := &tls.Config{}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				o.err = err
+				return
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				o.err = errors.New("no certificate found in " + caFile)
+				return
+			}
+			config.RootCAs = pool
+		}
+		if certFile != "" || keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				o.err = err
+				return
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		o.tlsConfig = config
+	}
+}
+
+func WithFramedTransport() SessionOption {
+	return func(o *sessionOptions) {
+		o.transport = framedTransport
+	}
+}
+
+func WithBufferedTransport(bufferSize int) SessionOption {
+	return func(o *sessionOptions) {
+		o.transport = bufferedTransport
+		o.bufferSize = bufferSize
+	}
+}
+
+func WithCompactProtocol() SessionOption {
+	return func(o *sessionOptions) {
+		o.compact = true
+	}
+}
+
+func WithBinaryProtocol() SessionOption {
+	return func(o *sessionOptions) {
+		o.compact = false
+	}
+}
+
+func newSessionOptions(opts []SessionOption) sessionOptions {
+	options := sessionOptions{
+		username:    DefaultUsername,
+		password:    DefaultPassword,
+		retryPolicy: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+func (o *sessionOptions) thriftConfiguration() *thrift.TConfiguration {
+	return &thrift.TConfiguration{
+		ConnectTimeout: o.connectTimeout,
+		SocketTimeout:  o.socketTimeout,
+		TLSConfig:      o.tlsConfig,
+	}
+}
+
+func (o *sessionOptions) openTransport(ctx context.Context, endpoint string) (thrift.TTransport, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	conf := o.thriftConfiguration()
+	dialer := &net.Dialer{Timeout: o.connectTimeout}
+	var socket thrift.TTransport
+	if o.tlsConfig != nil {
+		tlsDialer := tls.Dialer{NetDialer: dialer, Config: o.tlsConfig}
+		conn, err := tlsDialer.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		socket = thrift.NewTSSLSocketFromConnConf(conn, conf)
+	} else {
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		socket = thrift.NewTSocketFromConnConf(conn, conf)
+	}
+
+	switch o.transport {
+	case framedTransport:
+		return thrift.NewTFramedTransportConf(socket, conf), nil
+	case bufferedTransport:
+		bufferSize := o.bufferSize
+		if bufferSize <= 0 {
+			bufferSize = DefaultBufferSize
+		}
+		return thrift.NewTBufferedTransport(socket, bufferSize), nil
+	default:
+		return socket, nil
+	}
+}
+
+func (o *sessionOptions) protocolFactory() thrift.TProtocolFactory {
+	if o.compact {
+		return thrift.NewTCompactProtocolFactoryConf(o.thriftConfiguration())
+	}
+	return thrift.NewTBinaryProtocolFactoryConf(o.thriftConfiguration())
+}
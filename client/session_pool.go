@@ -0,0 +1,517 @@
+package client
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+const (
+	DefaultPoolMaxSize = 8
+)
+
+type PoolConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// 设置后忽略 Host 和 Port，会话可以在这些节点间故障转移
+	Endpoints []string
+
+	// 池中最多同时存在的会话数，包括空闲和已借出的会话
+	MaxSize int
+	// 空闲超过该时长的会话会被关闭，为 0 时不限制
+	IdleTimeout time.Duration
+	// 为 true 时借出空闲会话前先发送一次请求，检查服务端的会话是否仍然有效，无效时重新打开
+	ValidateOnBorrow bool
+	// 创建会话时使用的其他选项
+	Options []SessionOption
+}
+
+type idleSession struct {
+	session *Session
+	since   time.Time
+}
+
+// SessionPool 管理一组已打开的会话，可以被多个 goroutine 并发使用。
+// 通过 Get 借出的会话在使用完毕后必须通过 Put 归还
+type SessionPool struct {
+	config PoolConfig
+
+	mu      sync.Mutex
+	idle    []idleSession
+	isClose bool
+	// 已借出的会话，Put 只接受其中的会话
+	borrowed map[*Session]struct{}
+
+	// 每个借出或正在创建的会话占用一个令牌
+	tokens chan struct{}
+}
+
+func NewSessionPool(config PoolConfig) *SessionPool {
+	if config.Username == "" {
+		config.Username = DefaultUsername
+		config.Password = DefaultPassword
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultPoolMaxSize
+	}
+	return &SessionPool{
+		config:   config,
+		borrowed: make(map[*Session]struct{}),
+		tokens:   make(chan struct{}, config.MaxSize),
+	}
+}
+
+// Get 借出一个会话，池已满时等待其他会话归还。空闲会话的连接已断开时会先重新打开，
+// 设置了 ValidateOnBorrow 时还会检查服务端的会话是否有效，无法恢复的会话被丢弃
+func (p *SessionPool) Get(ctx context.Context) (*Session, error) {
+	select {
+	case p.tokens <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		session, err := p.popIdle()
+		if err != nil {
+			<-p.tokens
+			return nil, err
+		}
+		if session == nil {
+			break
+		}
+		if err := p.validate(ctx, session); err == nil {
+			return p.lend(session)
+		}
+		_ = session.Close()
+		if err := ctx.Err(); err != nil {
+			<-p.tokens
+			return nil, err
+		}
+	}
+
+	session := p.newSession()
+	if err := session.OpenContext(ctx); err != nil {
+		<-p.tokens
+		return nil, err
+	}
+	return p.lend(session)
+}
+
+// validate 检查空闲会话是否可用，连接已断开或服务端的会话已失效时重新打开
+func (p *SessionPool) validate(ctx context.Context, session *Session) error {
+	if session.isValid() {
+		if !p.config.ValidateOnBorrow {
+			return nil
+		}
+		_, err := session.GetReplicaNumContext(ctx)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	} else if session.isClose && !session.broken {
+		return ErrSessionClosed
+	}
+	return session.reconnect(ctx)
+}
+
+// lend 记录借出的会话，池在此期间被关闭时关闭该会话
+func (p *SessionPool) lend(session *Session) (*Session, error) {
+	p.mu.Lock()
+	if p.isClose {
+		p.mu.Unlock()
+		_ = session.Close()
+		<-p.tokens
+		return nil, ErrPoolClosed
+	}
+	p.borrowed[session] = struct{}{}
+	p.mu.Unlock()
+	return session, nil
+}
+
+func (p *SessionPool) newSession() *Session {
+	if len(p.config.Endpoints) > 0 {
+		return NewSessionWithEndpoints(p.config.Endpoints, p.config.Username, p.config.Password, p.config.Options...)
+	}
+	opts := append([]SessionOption{WithUser(p.config.Username, p.config.Password)}, p.config.Options...)
+	return NewSessionWithOptions(p.config.Host, p.config.Port, opts...)
+}
+
+// Put 归还通过 Get 借出的会话，不是从该池借出或已经归还的会话会被忽略。
+// 因连接故障而断开的会话仍然放回池中，下一次借出时重新打开
+func (p *SessionPool) Put(session *Session) {
+	if session == nil {
+		return
+	}
+
+	p.mu.Lock()
+	if _, ok := p.borrowed[session]; !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.borrowed, session)
+	defer func() {
+		<-p.tokens
+	}()
+
+	if p.isClose || (session.isClose && !session.broken) {
+		p.mu.Unlock()
+		_ = session.Close()
+		return
+	}
+	p.idle = append(p.idle, idleSession{session: session, since: time.Now()})
+	expired := p.evictExpired()
+	p.mu.Unlock()
+
+	for _, s := range expired {
+		_ = s.Close()
+	}
+}
+
+func (p *SessionPool) Close() error {
+	p.mu.Lock()
+	if p.isClose {
+		p.mu.Unlock()
+		return nil
+	}
+	p.isClose = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var err error
+	for _, item := range idle {
+		if e := item.session.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// popIdle 取出最近归还的空闲会话，没有可用会话时返回 nil
+func (p *SessionPool) popIdle() (*Session, error) {
+	p.mu.Lock()
+	if p.isClose {
+		p.mu.Unlock()
+		return nil, ErrPoolClosed
+	}
+	expired := p.evictExpired()
+	var session *Session
+	if n := len(p.idle); n > 0 {
+		session = p.idle[n-1].session
+		p.idle = p.idle[:n-1]
+	}
+	p.mu.Unlock()
+
+	for _, s := range expired {
+		_ = s.Close()
+	}
+	return session, nil
+}
+
+// evictExpired 移除空闲超时的会话并返回，调用方需持有锁，并在释放锁后关闭这些会话
+func (p *SessionPool) evictExpired() []*Session {
+	if p.config.IdleTimeout <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(-p.config.IdleTimeout)
+	// idle 按归还时间递增排列
+	n := 0
+	for n < len(p.idle) && p.idle[n].since.Before(deadline) {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	expired := make([]*Session, 0, n)
+	for _, item := range p.idle[:n] {
+		expired = append(expired, item.session)
+	}
+	p.idle = append(p.idle[:0], p.idle[n:]...)
+	return expired
+}
+
+func (p *SessionPool) do(ctx context.Context, fn func(session *Session) error) error {
+	session, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Put(session)
+	return fn(session)
+}
+
+func (p *SessionPool) ListTimeSeries() ([]TimeSeries, error) {
+	return p.ListTimeSeriesContext(context.Background())
+}
+
+func (p *SessionPool) ListTimeSeriesContext(ctx context.Context) ([]TimeSeries, error) {
+	var ret []TimeSeries
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ListTimeSeriesContext(ctx)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) InsertRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.InsertRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (p *SessionPool) InsertRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.InsertRowRecordsContext(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	})
+}
+
+func (p *SessionPool) InsertNonAlignedRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.InsertNonAlignedRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (p *SessionPool) InsertNonAlignedRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.InsertNonAlignedRowRecordsContext(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	})
+}
+
+func (p *SessionPool) InsertColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.InsertColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (p *SessionPool) InsertColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.InsertColumnRecordsContext(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	})
+}
+
+func (p *SessionPool) InsertNonAlignedColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.InsertNonAlignedColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (p *SessionPool) InsertNonAlignedColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.InsertNonAlignedColumnRecordsContext(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	})
+}
+
+func (p *SessionPool) DeleteData(path string, startTime, endTime int64, tagsList map[string][]string) error {
+	return p.DeleteDataContext(context.Background(), path, startTime, endTime, tagsList)
+}
+
+func (p *SessionPool) DeleteDataContext(ctx context.Context, path string, startTime, endTime int64, tagsList map[string][]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.DeleteDataContext(ctx, path, startTime, endTime, tagsList)
+	})
+}
+
+func (p *SessionPool) BatchDeleteData(paths []string, startTime, endTime int64, tagsList map[string][]string) error {
+	return p.BatchDeleteDataContext(context.Background(), paths, startTime, endTime, tagsList)
+}
+
+func (p *SessionPool) BatchDeleteDataContext(ctx context.Context, paths []string, startTime, endTime int64, tagsList map[string][]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.BatchDeleteDataContext(ctx, paths, startTime, endTime, tagsList)
+	})
+}
+
+func (p *SessionPool) Query(paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return p.QueryContext(context.Background(), paths, startTime, endTime, tagList)
+}
+
+func (p *SessionPool) QueryContext(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	var ret *QueryDataSet
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.QueryContext(ctx, paths, startTime, endTime, tagList)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) DownSampleQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return p.DownSampleQueryContext(context.Background(), paths, startTime, endTime, aggregateType, precision, tagList)
+}
+
+func (p *SessionPool) DownSampleQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*QueryDataSet, error) {
+	var ret *QueryDataSet
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.DownSampleQueryContext(ctx, paths, startTime, endTime, aggregateType, precision, tagList)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) AggregateQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error) {
+	return p.AggregateQueryContext(context.Background(), paths, startTime, endTime, aggregateType, tagList)
+}
+
+func (p *SessionPool) AggregateQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*AggregateQueryDataSet, error) {
+	var ret *AggregateQueryDataSet
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.AggregateQueryContext(ctx, paths, startTime, endTime, aggregateType, tagList)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) LastQuery(paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	return p.LastQueryContext(context.Background(), paths, startTime, tagList)
+}
+
+func (p *SessionPool) LastQueryContext(ctx context.Context, paths []string, startTime int64, tagList map[string][]string) (*QueryDataSet, error) {
+	var ret *QueryDataSet
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.LastQueryContext(ctx, paths, startTime, tagList)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) ExecuteSQL(sql string) (*SQLDataSet, error) {
+	return p.ExecuteSQLContext(context.Background(), sql)
+}
+
+func (p *SessionPool) ExecuteSQLContext(ctx context.Context, sql string) (*SQLDataSet, error) {
+	var ret *SQLDataSet
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ExecuteSQLContext(ctx, sql)
+		return err
+	})
+	return ret, err
+}
+
+// ExecuteQuery 借出的会话在返回的 StreamDataSet 关闭后才归还
+func (p *SessionPool) ExecuteQuery(statement string) (*StreamDataSet, error) {
+	return p.ExecuteQueryContext(context.Background(), statement)
+}
+
+func (p *SessionPool) ExecuteQueryContext(ctx context.Context, statement string) (*StreamDataSet, error) {
+	return p.ExecuteQueryWithFetchSizeContext(ctx, statement, math.MaxInt32)
+}
+
+func (p *SessionPool) ExecuteQueryWithFetchSize(statement string, fetchSize int32) (*StreamDataSet, error) {
+	return p.ExecuteQueryWithFetchSizeContext(context.Background(), statement, fetchSize)
+}
+
+func (p *SessionPool) ExecuteQueryWithFetchSizeContext(ctx context.Context, statement string, fetchSize int32) (*StreamDataSet, error) {
+	session, err := p.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := session.ExecuteQueryWithFetchSizeContext(ctx, statement, fetchSize)
+	if err != nil {
+		p.Put(session)
+		return nil, err
+	}
+	ret.release = func() {
+		p.Put(session)
+	}
+	return ret, nil
+}
+
+func (p *SessionPool) DeleteTimeSeries(path string) error {
+	return p.DeleteTimeSeriesContext(context.Background(), path)
+}
+
+func (p *SessionPool) DeleteTimeSeriesContext(ctx context.Context, path string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.DeleteTimeSeriesContext(ctx, path)
+	})
+}
+
+func (p *SessionPool) BatchDeleteTimeSeries(paths []string) error {
+	return p.BatchDeleteTimeSeriesContext(context.Background(), paths)
+}
+
+func (p *SessionPool) BatchDeleteTimeSeriesContext(ctx context.Context, paths []string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.BatchDeleteTimeSeriesContext(ctx, paths)
+	})
+}
+
+func (p *SessionPool) GetReplicaNum() (int32, error) {
+	return p.GetReplicaNumContext(context.Background())
+}
+
+func (p *SessionPool) GetReplicaNumContext(ctx context.Context) (int32, error) {
+	var ret int32
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.GetReplicaNumContext(ctx)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) GetClusterInfo() (*ClusterInfo, error) {
+	return p.GetClusterInfoContext(context.Background())
+}
+
+func (p *SessionPool) GetClusterInfoContext(ctx context.Context) (*ClusterInfo, error) {
+	var ret *ClusterInfo
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.GetClusterInfoContext(ctx)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) AddStorageEngine(ip, port, engineType string, extra map[string]string) error {
+	return p.AddStorageEngineContext(context.Background(), ip, port, engineType, extra)
+}
+
+func (p *SessionPool) AddStorageEngineContext(ctx context.Context, ip, port, engineType string, extra map[string]string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.AddStorageEngineContext(ctx, ip, port, engineType, extra)
+	})
+}
+
+func (p *SessionPool) BatchAddStorageEngine(engines []*rpc.StorageEngine) error {
+	return p.BatchAddStorageEngineContext(context.Background(), engines)
+}
+
+func (p *SessionPool) BatchAddStorageEngineContext(ctx context.Context, engines []*rpc.StorageEngine) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.BatchAddStorageEngineContext(ctx, engines)
+	})
+}
+
+func (p *SessionPool) AddUser(username, password string, auths []rpc.AuthType) error {
+	return p.AddUserContext(context.Background(), username, password, auths)
+}
+
+func (p *SessionPool) AddUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.AddUserContext(ctx, username, password, auths)
+	})
+}
+
+func (p *SessionPool) DeleteUser(username string) error {
+	return p.DeleteUserContext(context.Background(), username)
+}
+
+func (p *SessionPool) DeleteUserContext(ctx context.Context, username string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.DeleteUserContext(ctx, username)
+	})
+}
+
+func (p *SessionPool) UpdateUser(username, password string, auths []rpc.AuthType) error {
+	return p.UpdateUserContext(context.Background(), username, password, auths)
+}
+
+func (p *SessionPool) UpdateUserContext(ctx context.Context, username, password string, auths []rpc.AuthType) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.UpdateUserContext(ctx, username, password, auths)
+	})
+}
+
+func (p *SessionPool) UpdateUserAuths(username string, auths []rpc.AuthType) error {
+	return p.UpdateUserAuthsContext(context.Background(), username, auths)
+}
+
+func (p *SessionPool) UpdateUserAuthsContext(ctx context.Context, username string, auths []rpc.AuthType) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.UpdateUserAuthsContext(ctx, username, auths)
+	})
+}
@@ -0,0 +1,162 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+)
+
+func newPool(t *testing.T, maxSize int) *client.SessionPool {
+	t.Helper()
+	return newPoolWithConfig(t, newServer(t).PoolConfig(), maxSize)
+}
+
+func newPoolWithConfig(t *testing.T, config client.PoolConfig, maxSize int) *client.SessionPool {
+	t.Helper()
+	config.MaxSize = maxSize
+	pool := client.NewSessionPool(config)
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+	return pool
+}
+
+func TestSessionPoolGetPut(t *testing.T) {
+	pool := newPool(t, 2)
+	ctx := context.Background()
+	first, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("Get returned the same session twice")
+	}
+
+	// 池已满时 Get 等待到 ctx 结束
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get on a full pool: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	pool.Put(first)
+	again, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Error("Get did not reuse the idle session")
+	}
+	if _, err := again.ListTimeSeries(); err != nil {
+		t.Errorf("reused session: %v", err)
+	}
+	pool.Put(again)
+	pool.Put(second)
+}
+
+func TestSessionPoolClose(t *testing.T) {
+	pool := newPool(t, 1)
+	session, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后归还的会话被关闭
+	pool.Put(session)
+	if _, err := session.ListTimeSeries(); !errors.Is(err, client.ErrSessionClosed) {
+		t.Errorf("session put after Close: err = %v, want %v", err, client.ErrSessionClosed)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, client.ErrPoolClosed) {
+		t.Errorf("Get after Close: err = %v, want %v", err, client.ErrPoolClosed)
+	}
+}
+
+func TestSessionPoolPutUnknownSession(t *testing.T) {
+	tests := []struct {
+		name string
+		put  func(pool *client.SessionPool, borrowed, other *client.Session)
+	}{
+		{"put twice", func(pool *client.SessionPool, borrowed, other *client.Session) {
+			pool.Put(borrowed)
+			pool.Put(borrowed)
+		}},
+		{"put a session from elsewhere", func(pool *client.SessionPool, borrowed, other *client.Session) {
+			pool.Put(borrowed)
+			pool.Put(other)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newPool(t, 1)
+			session, err := pool.Get(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			other := openSession(t, newServer(t))
+
+			done := make(chan struct{})
+			go func() {
+				tt.put(pool, session, other)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Put blocked")
+			}
+
+			// 只归还了一个会话，池中只有一个令牌可用
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			first, err := pool.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first != session {
+				t.Error("Get did not return the idle session")
+			}
+			if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("second Get: err = %v, want %v", err, context.DeadlineExceeded)
+			}
+			pool.Put(first)
+		})
+	}
+}
+
+func TestSessionPoolReconnectOnBorrow(t *testing.T) {
+	for _, validate := range []bool{false, true} {
+		server := newServer(t)
+		config := server.PoolConfig()
+		config.ValidateOnBorrow = validate
+		pool := newPoolWithConfig(t, config, 1)
+		session, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.Put(session)
+		if err := server.Restart(); err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("validate %v: %v", validate, err)
+		}
+		if again != session {
+			t.Errorf("validate %v: the broken session was not reconnected and reused", validate)
+		}
+		if _, err := again.ListTimeSeries(); err != nil {
+			t.Errorf("validate %v: %v", validate, err)
+		}
+		pool.Put(again)
+	}
+}
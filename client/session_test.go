@@ -0,0 +1,251 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/iginxtest"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func newServer(t *testing.T) *iginxtest.Server {
+	t.Helper()
+	server, err := iginxtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	return server
+}
+
+func openSession(t *testing.T, server *iginxtest.Server, opts ...client.SessionOption) *client.Session {
+	t.Helper()
+	session, err := server.OpenSession(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = session.Close()
+	})
+	return session
+}
+
+func TestInsertAndQuery(t *testing.T) {
+	paths := []string{"a.b", "a.c"}
+	types := []rpc.DataType{rpc.DataType_LONG, rpc.DataType_BINARY}
+	timestamps := []int64{1, 2, 3}
+	rows := [][]interface{}{{int64(1), "x"}, {nil, "y"}, {int64(3), nil}}
+	columns := [][]interface{}{{int64(1), nil, int64(3)}, {"x", "y", nil}}
+
+	tests := []struct {
+		name   string
+		insert func(s *client.Session) error
+	}{
+		{"row", func(s *client.Session) error {
+			return s.InsertRowRecords(paths, timestamps, rows, types, nil)
+		}},
+		{"non-aligned row", func(s *client.Session) error {
+			return s.InsertNonAlignedRowRecords(paths, timestamps, rows, types, nil)
+		}},
+		{"column", func(s *client.Session) error {
+			return s.InsertColumnRecords(paths, timestamps, columns, types, nil)
+		}},
+		{"non-aligned column", func(s *client.Session) error {
+			return s.InsertNonAlignedColumnRecords(paths, timestamps, columns, types, nil)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := openSession(t, newServer(t))
+			if err := tt.insert(session); err != nil {
+				t.Fatal(err)
+			}
+			dataSet, err := session.Query([]string{"a.*"}, 0, 10, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dataSet.Paths, paths) {
+				t.Errorf("paths = %v, want %v", dataSet.Paths, paths)
+			}
+			if !reflect.DeepEqual(dataSet.Types, types) {
+				t.Errorf("types = %v, want %v", dataSet.Types, types)
+			}
+			if !reflect.DeepEqual(dataSet.Timestamps, timestamps) {
+				t.Errorf("timestamps = %v, want %v", dataSet.Timestamps, timestamps)
+			}
+			if !reflect.DeepEqual(dataSet.Values, rows) {
+				t.Errorf("values = %v, want %v", dataSet.Values, rows)
+			}
+		})
+	}
+}
+
+func TestStreamFetch(t *testing.T) {
+	server := newServer(t)
+	session := openSession(t, server)
+	var timestamps []int64
+	var values [][]interface{}
+	for i := int64(1); i <= 5; i++ {
+		timestamps = append(timestamps, i)
+		values = append(values, []interface{}{i * 10})
+	}
+	if err := session.InsertRowRecords([]string{"a.b"}, timestamps, values, []rpc.DataType{rpc.DataType_LONG}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fetchSize := range []int32{1, 2, 5, 10} {
+		dataSet, err := session.ExecuteQueryWithFetchSize("SELECT * FROM a", fetchSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotTimestamps []int64
+		var gotValues [][]interface{}
+		for dataSet.Next() {
+			gotTimestamps = append(gotTimestamps, dataSet.Timestamp())
+			gotValues = append(gotValues, dataSet.Row())
+		}
+		if err := dataSet.Err(); err != nil {
+			t.Fatalf("fetch size %d: %v", fetchSize, err)
+		}
+		if !reflect.DeepEqual(gotTimestamps, timestamps) || !reflect.DeepEqual(gotValues, values) {
+			t.Errorf("fetch size %d: got %v %v, want %v %v", fetchSize, gotTimestamps, gotValues, timestamps, values)
+		}
+	}
+}
+
+func TestReconnectAfterServerRestart(t *testing.T) {
+	server := newServer(t)
+	session := openSession(t, server)
+	err := session.InsertRowRecords([]string{"a.b"}, []int64{1}, [][]interface{}{{int64(1)}}, []rpc.DataType{rpc.DataType_LONG}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 幂等的调用会重新打开会话并重试
+	dataSet, err := session.Query([]string{"a.b"}, 0, 10, nil)
+	if err != nil {
+		t.Fatalf("query after restart: %v", err)
+	}
+	if !reflect.DeepEqual(dataSet.Timestamps, []int64{1}) {
+		t.Errorf("timestamps = %v, want [1]", dataSet.Timestamps)
+	}
+	err = session.InsertRowRecords([]string{"a.b"}, []int64{2}, [][]interface{}{{int64(2)}}, []rpc.DataType{rpc.DataType_LONG}, nil)
+	if err != nil {
+		t.Fatalf("insert after reconnect: %v", err)
+	}
+}
+
+func TestMixedTypeRejection(t *testing.T) {
+	tests := []struct {
+		name   string
+		before func(s *client.Session) error
+		insert func(s *client.Session) error
+	}{
+		{
+			name: "existing series",
+			before: func(s *client.Session) error {
+				return s.InsertRowRecords([]string{"a.b"}, []int64{1}, [][]interface{}{{int64(1)}}, []rpc.DataType{rpc.DataType_LONG}, nil)
+			},
+			insert: func(s *client.Session) error {
+				return s.InsertRowRecords([]string{"a.b"}, []int64{2}, [][]interface{}{{1.5}}, []rpc.DataType{rpc.DataType_DOUBLE}, nil)
+			},
+		},
+		{
+			name: "single write",
+			insert: func(s *client.Session) error {
+				return s.InsertNonAlignedColumnRecords([]string{"a.b", "a.b"}, []int64{1, 2},
+					[][]interface{}{{int64(1), nil}, {nil, 2.5}},
+					[]rpc.DataType{rpc.DataType_LONG, rpc.DataType_DOUBLE}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			session := openSession(t, server)
+			if tt.before != nil {
+				if err := tt.before(session); err != nil {
+					t.Fatal(err)
+				}
+			}
+			points := server.PointCount()
+			err := tt.insert(session)
+			var statusErr *client.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("err = %v, want a StatusError", err)
+			}
+			if got := server.PointCount(); got != points {
+				t.Errorf("point count = %d, want %d", got, points)
+			}
+		})
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name    string
+		latency time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+		// 为 true 时调用被中断，会话应被标记为故障
+		wantBroken bool
+	}{
+		{
+			name: "cancelled before call",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name:    "cancelled during blocking call",
+			latency: 2 * time.Second,
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			wantErr:    context.DeadlineExceeded,
+			wantBroken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			session := openSession(t, server)
+			server.SetLatency(tt.latency)
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			_, err := session.ListTimeSeriesContext(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("call returned after %v, want it to be interrupted", elapsed)
+			}
+			if session.IsBroken() != tt.wantBroken {
+				t.Errorf("broken = %v, want %v", session.IsBroken(), tt.wantBroken)
+			}
+
+			// 之后的调用会重新打开会话
+			server.SetLatency(0)
+			if _, err := session.ListTimeSeries(); err != nil {
+				t.Fatalf("call after cancellation: %v", err)
+			}
+			if session.IsBroken() {
+				t.Error("session is still broken after a successful call")
+			}
+		})
+	}
+}
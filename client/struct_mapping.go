@@ -0,0 +1,571 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// 结构体字段通过 iginx 标签映射到序列：
+//
+//	type Reading struct {
+//		Time        int64    `iginx:",time"`
+//		Host        string   `iginx:"host,tag"`
+//		Temperature float64  `iginx:"temperature,tag=host"`
+//		Humidity    *float64 `iginx:"humidity"`
+//	}
+//
+// 不带选项的字段是数据字段，名称为空时使用字段名；tag 选项表示标签字段，类型为 string；
+// tag=k 限定数据字段只使用名为 k 的标签，可以出现多次，不限定时使用所有标签字段；
+// time 选项表示时间戳字段，类型为 int64 或 time.Time（毫秒）。
+// 指针类型的数据字段为 nil 时表示空值；没有 iginx 标签或标签为 "-" 的字段会被忽略
+const structTagName = "iginx"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type structField struct {
+	index    []int
+	name     string
+	dataType rpc.DataType
+	isPtr    bool
+	// 数据字段使用的标签，为 nil 时使用所有标签字段
+	tagKeys []string
+}
+
+type structMapping struct {
+	timeIndex  []int
+	timeIsTime bool
+	fields     []structField
+	tags       []structField
+}
+
+var structMappings sync.Map
+
+func getStructMapping(t reflect.Type) (*structMapping, error) {
+	if m, ok := structMappings.Load(t); ok {
+		return m.(*structMapping), nil
+	}
+	m, err := newStructMapping(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := structMappings.LoadOrStore(t, m)
+	return actual.(*structMapping), nil
+}
+
+func newStructMapping(t reflect.Type) (*structMapping, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%v is not a struct", t)
+	}
+	m := &structMapping{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup(structTagName)
+		if !ok || tag == "-" || field.PkgPath != "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = field.Name
+		}
+		isTag, isTime := false, false
+		var tagKeys []string
+		for _, option := range parts[1:] {
+			switch {
+			case option == "tag":
+				isTag = true
+			case option == "time":
+				isTime = true
+			case strings.HasPrefix(option, "tag="):
+				tagKeys = append(tagKeys, strings.TrimPrefix(option, "tag="))
+			case option == "":
+			default:
+				return nil, fmt.Errorf("field %s of %v: unknown option %q", field.Name, t, option)
+			}
+		}
+
+		fieldType := field.Type
+		isPtr := fieldType.Kind() == reflect.Ptr
+		if isPtr {
+			fieldType = fieldType.Elem()
+		}
+		switch {
+		case isTime:
+			if m.timeIndex != nil {
+				return nil, fmt.Errorf("%v has more than one time field", t)
+			}
+			if isPtr || (fieldType != timeType && fieldType.Kind() != reflect.Int64) {
+				return nil, fmt.Errorf("time field %s of %v should be int64 or time.Time", field.Name, t)
+			}
+			m.timeIndex = field.Index
+			m.timeIsTime = fieldType == timeType
+		case isTag:
+			if fieldType.Kind() != reflect.String {
+				return nil, fmt.Errorf("tag field %s of %v should be string", field.Name, t)
+			}
+			m.tags = append(m.tags, structField{index: field.Index, name: name, isPtr: isPtr})
+		default:
+			dataType, ok := dataTypeOfKind(fieldType)
+			if !ok {
+				return nil, fmt.Errorf("field %s of %v: unsupported type %v", field.Name, t, field.Type)
+			}
+			m.fields = append(m.fields, structField{index: field.Index, name: name, dataType: dataType, isPtr: isPtr, tagKeys: tagKeys})
+		}
+	}
+	if len(m.fields) == 0 {
+		return nil, fmt.Errorf("%v has no field with iginx tag", t)
+	}
+	for _, field := range m.fields {
+		for _, key := range field.tagKeys {
+			if m.tagField(key) == nil {
+				return nil, fmt.Errorf("field %s of %v refers to unknown tag %q", field.name, t, key)
+			}
+		}
+	}
+	return m, nil
+}
+
+func dataTypeOfKind(t reflect.Type) (rpc.DataType, bool) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return rpc.DataType_BOOLEAN, true
+	case reflect.Int32:
+		return rpc.DataType_INTEGER, true
+	case reflect.Int, reflect.Int64:
+		return rpc.DataType_LONG, true
+	case reflect.Float32:
+		return rpc.DataType_FLOAT, true
+	case reflect.Float64:
+		return rpc.DataType_DOUBLE, true
+	case reflect.String:
+		return rpc.DataType_BINARY, true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return rpc.DataType_BINARY, true
+		}
+	}
+	return 0, false
+}
+
+func (m *structMapping) tagField(key string) *structField {
+	for i := range m.tags {
+		if m.tags[i].name == key {
+			return &m.tags[i]
+		}
+	}
+	return nil
+}
+
+// toRecords 将一个结构体转换为记录，标签不同的数据字段会被放入不同的记录
+func (m *structMapping) toRecords(prefix string, v reflect.Value) ([]*Record, error) {
+	if m.timeIndex == nil {
+		return nil, fmt.Errorf("%v has no time field", v.Type())
+	}
+	var timestamp int64
+	if m.timeIsTime {
+		timestamp = v.FieldByIndex(m.timeIndex).Interface().(time.Time).UnixNano() / int64(time.Millisecond)
+	} else {
+		timestamp = v.FieldByIndex(m.timeIndex).Int()
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range m.tags {
+		value := v.FieldByIndex(tag.index)
+		if tag.isPtr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		tags[tag.name] = value.String()
+	}
+
+	var records []*Record
+	recordIndex := make(map[string]*Record)
+	for _, field := range m.fields {
+		value := v.FieldByIndex(field.index)
+		if field.isPtr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		fieldTags := tags
+		if field.tagKeys != nil {
+			fieldTags = make(map[string]string)
+			for _, key := range field.tagKeys {
+				if tagValue, ok := tags[key]; ok {
+					fieldTags[key] = tagValue
+				}
+			}
+		}
+		key := tagsKey(fieldTags)
+		record, ok := recordIndex[key]
+		if !ok {
+			record = NewRecord(timestamp)
+			for k, tagValue := range fieldTags {
+				record.Tag(k, tagValue)
+			}
+			recordIndex[key] = record
+			records = append(records, record)
+		}
+		record.AddWithType(joinPath(prefix, field.name), fieldValue(value, field.dataType), field.dataType)
+	}
+	return records, nil
+}
+
+func fieldValue(v reflect.Value, dataType rpc.DataType) interface{} {
+	switch dataType {
+	case rpc.DataType_BOOLEAN:
+		return v.Bool()
+	case rpc.DataType_INTEGER:
+		return int32(v.Int())
+	case rpc.DataType_LONG:
+		return v.Int()
+	case rpc.DataType_FLOAT:
+		return float32(v.Float())
+	case rpc.DataType_DOUBLE:
+		return v.Float()
+	default:
+		if v.Kind() == reflect.Slice {
+			return string(v.Bytes())
+		}
+		return v.String()
+	}
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// InsertStructs 将结构体切片写入以 prefix 为前缀的序列，items 的类型为 []T 或 []*T
+func (s *Session) InsertStructs(prefix string, items interface{}) error {
+	return s.InsertStructsContext(context.Background(), prefix, items)
+}
+
+func (s *Session) InsertStructsContext(ctx context.Context, prefix string, items interface{}) error {
+	records, err := structsToRecords(prefix, items)
+	if err != nil {
+		return err
+	}
+	return s.InsertRecordsContext(ctx, records...)
+}
+
+func (p *SessionPool) InsertStructs(prefix string, items interface{}) error {
+	return p.InsertStructsContext(context.Background(), prefix, items)
+}
+
+func (p *SessionPool) InsertStructsContext(ctx context.Context, prefix string, items interface{}) error {
+	records, err := structsToRecords(prefix, items)
+	if err != nil {
+		return err
+	}
+	return p.InsertRecordsContext(ctx, records...)
+}
+
+func structsToRecords(prefix string, items interface{}) ([]*Record, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("items should be a slice of struct, got %T", items)
+	}
+	elemType := v.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	m, err := getStructMapping(elemType)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if isPtr {
+			if item.IsNil() {
+				return nil, fmt.Errorf("items[%d] is nil", i)
+			}
+			item = item.Elem()
+		}
+		itemRecords, err := m.toRecords(prefix, item)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, itemRecords...)
+	}
+	return records, nil
+}
+
+type scanColumn struct {
+	// 对应的数据字段，-1 表示没有对应的字段
+	field int
+	// 对应的标签组
+	group int
+}
+
+// structScanner 将结果集中的行写入结构体切片。每一行中标签相同的列组成一个结构体，标签字段由列的标签填充
+type structScanner struct {
+	mapping  *structMapping
+	slice    reflect.Value
+	elemType reflect.Type
+	isPtr    bool
+	columns  []scanColumn
+	groups   []map[string]string
+}
+
+func newStructScanner(dest interface{}, paths []string, tagsList []map[string]string) (*structScanner, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("dest should be a pointer to slice of struct, got %T", dest)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	m, err := getStructMapping(elemType)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := &structScanner{
+		mapping:  m,
+		slice:    slice,
+		elemType: elemType,
+		isPtr:    isPtr,
+	}
+	groupIndex := make(map[string]int)
+	for i, path := range paths {
+		name, tags := splitColumnTags(path)
+		if tagsList != nil && i < len(tagsList) {
+			tags = tagsList[i]
+		}
+		column := scanColumn{field: m.matchField(name), group: -1}
+		if column.field >= 0 {
+			group := make(map[string]string)
+			for _, tag := range m.tags {
+				if value, ok := tags[tag.name]; ok {
+					group[tag.name] = value
+				}
+			}
+			key := tagsKey(group)
+			index, ok := groupIndex[key]
+			if !ok {
+				index = len(scanner.groups)
+				groupIndex[key] = index
+				scanner.groups = append(scanner.groups, group)
+			}
+			column.group = index
+		}
+		scanner.columns = append(scanner.columns, column)
+	}
+	return scanner, nil
+}
+
+// matchField 返回与序列对应的数据字段，序列等于字段名或以 "." 加字段名结尾时匹配，多个字段匹配时取名称最长的
+func (m *structMapping) matchField(path string) int {
+	match := -1
+	for i, field := range m.fields {
+		if path != field.name && !strings.HasSuffix(path, "."+field.name) {
+			continue
+		}
+		if match < 0 || len(field.name) > len(m.fields[match].name) {
+			match = i
+		}
+	}
+	return match
+}
+
+// splitColumnTags 拆分形如 a.b{k1=v1,k2=v2} 的列名
+func splitColumnTags(column string) (string, map[string]string) {
+	start := strings.IndexByte(column, '{')
+	if start < 0 || !strings.HasSuffix(column, "}") {
+		return column, nil
+	}
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(column[start+1:len(column)-1], ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) == 2 {
+			tags[kv[0]] = kv[1]
+		}
+	}
+	return column[:start], tags
+}
+
+func (s *structScanner) scanRow(timestamp int64, hasTime bool, values []interface{}) error {
+	items := make([]reflect.Value, len(s.groups))
+	for i, value := range values {
+		if i >= len(s.columns) || value == nil {
+			continue
+		}
+		column := s.columns[i]
+		if column.field < 0 {
+			continue
+		}
+		item := items[column.group]
+		if !item.IsValid() {
+			item = reflect.New(s.elemType).Elem()
+			items[column.group] = item
+		}
+		field := s.mapping.fields[column.field]
+		if err := setFieldValue(item.FieldByIndex(field.index), value); err != nil {
+			return fmt.Errorf("field %s: %s", field.name, err)
+		}
+	}
+
+	for i, item := range items {
+		if !item.IsValid() {
+			continue
+		}
+		if hasTime && s.mapping.timeIndex != nil {
+			timeField := item.FieldByIndex(s.mapping.timeIndex)
+			if s.mapping.timeIsTime {
+				timeField.Set(reflect.ValueOf(time.Unix(0, timestamp*int64(time.Millisecond))))
+			} else {
+				timeField.SetInt(timestamp)
+			}
+		}
+		for key, value := range s.groups[i] {
+			tag := s.mapping.tagField(key)
+			tagField := item.FieldByIndex(tag.index)
+			if tag.isPtr {
+				value := value
+				tagField.Set(reflect.ValueOf(&value))
+			} else {
+				tagField.SetString(value)
+			}
+		}
+		if s.isPtr {
+			item = item.Addr()
+		}
+		s.slice.Set(reflect.Append(s.slice, item))
+	}
+	return nil
+}
+
+func setFieldValue(field reflect.Value, value interface{}) error {
+	target := field
+	if field.Kind() == reflect.Ptr {
+		target = reflect.New(field.Type().Elem()).Elem()
+	}
+	v := reflect.ValueOf(value)
+	switch target.Kind() {
+	case reflect.Bool:
+		if v.Kind() != reflect.Bool {
+			return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+		}
+		target.SetBool(v.Bool())
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if v.Kind() != reflect.Int32 && v.Kind() != reflect.Int64 {
+			return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+		}
+		if target.OverflowInt(v.Int()) {
+			return fmt.Errorf("%v overflows %v", value, field.Type())
+		}
+		target.SetInt(v.Int())
+	case reflect.Float32, reflect.Float64:
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			target.SetFloat(v.Float())
+		case reflect.Int32, reflect.Int64:
+			target.SetFloat(float64(v.Int()))
+		default:
+			return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+		}
+	case reflect.String:
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+		}
+		target.SetString(v.String())
+	case reflect.Slice:
+		if v.Kind() != reflect.String {
+			return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+		}
+		target.SetBytes([]byte(v.String()))
+	default:
+		return fmt.Errorf("cannot scan %v(%T) into %v", value, value, field.Type())
+	}
+	if field.Kind() == reflect.Ptr {
+		field.Set(target.Addr())
+	}
+	return nil
+}
+
+// ScanInto 将结果集追加到 dest 指向的结构体切片中，dest 的类型为 *[]T 或 *[]*T
+func (s *QueryDataSet) ScanInto(dest interface{}) error {
+	scanner, err := newStructScanner(dest, s.Paths, s.Tags)
+	if err != nil {
+		return err
+	}
+	for i, values := range s.Values {
+		hasTime := i < len(s.Timestamps)
+		var timestamp int64
+		if hasTime {
+			timestamp = s.Timestamps[i]
+		}
+		if err := scanner.scanRow(timestamp, hasTime, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanInto 读取剩余的所有行并追加到 dest 指向的结构体切片中，dest 的类型为 *[]T 或 *[]*T
+func (s *StreamDataSet) ScanInto(dest interface{}) error {
+	return s.ScanIntoContext(context.Background(), dest)
+}
+
+func (s *StreamDataSet) ScanIntoContext(ctx context.Context, dest interface{}) error {
+	timeColumn := streamTimeColumn(s.columns, s.types)
+	columns, tags := s.columns, s.tags
+	if timeColumn >= 0 {
+		columns = columns[timeColumn+1:]
+		if len(tags) > timeColumn {
+			tags = tags[timeColumn+1:]
+		}
+	}
+	scanner, err := newStructScanner(dest, columns, tags)
+	if err != nil {
+		return err
+	}
+	for s.HasMoreContext(ctx) {
+		values := s.NextRowContext(ctx)
+		var timestamp int64
+		hasTime := false
+		if timeColumn >= 0 {
+			if t, ok := values[timeColumn].(int64); ok {
+				timestamp, hasTime = t, true
+			}
+			values = values[timeColumn+1:]
+		}
+		if err := scanner.scanRow(timestamp, hasTime, values); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// streamTimeColumn 返回流式结果中时间戳列的下标，时间戳列为第一列，名称为 time 或 key，没有时返回 -1
+func streamTimeColumn(columns []string, types []rpc.DataType) int {
+	if len(columns) == 0 || len(types) == 0 || types[0] != rpc.DataType_LONG {
+		return -1
+	}
+	switch strings.ToLower(columns[0]) {
+	case "time", "key", "timestamp":
+		return 0
+	}
+	return -1
+}
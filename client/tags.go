@@ -0,0 +1,191 @@
+package client
+
+// MatchTags 判断 tags 是否包含 filter 中所有的键值对，filter 为空时总是匹配
+func MatchTags(tags, filter map[string]string) bool {
+	for k, v := range filter {
+		if value, ok := tags[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// formatColumn 生成形如 a.b{k1=v1,k2=v2} 的列名，标签按键排序
+func formatColumn(path string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return path
+	}
+	return path + tagsKey(tags)
+}
+
+func columnTags(tagsList []map[string]string, index int) map[string]string {
+	if index < len(tagsList) {
+		return tagsList[index]
+	}
+	return nil
+}
+
+// groupColumns 按标签 key 的值对列分组，没有该标签的列分到值为空字符串的组中
+func groupColumns(size int, tagsList []map[string]string, key string) map[string][]int {
+	groups := make(map[string][]int)
+	for i := 0; i < size; i++ {
+		value := columnTags(tagsList, i)[key]
+		groups[value] = append(groups[value], i)
+	}
+	return groups
+}
+
+func filterColumns(size int, tagsList []map[string]string, filter map[string]string) []int {
+	var columns []int
+	for i := 0; i < size; i++ {
+		if MatchTags(columnTags(tagsList, i), filter) {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+// ColumnName 返回带标签的列名，例如 a.b{k1=v1,k2=v2}
+func (s *QueryDataSet) ColumnName(index int) string {
+	return formatColumn(s.Paths[index], columnTags(s.Tags, index))
+}
+
+// FilterByTags 返回只包含标签与 filter 匹配的列的数据集，所选列全为空的行会被去掉
+func (s *QueryDataSet) FilterByTags(filter map[string]string) *QueryDataSet {
+	return s.selectColumns(filterColumns(len(s.Paths), s.Tags, filter))
+}
+
+// GroupByTag 按标签 key 的值对列分组，没有该标签的列分到键为空字符串的组中
+func (s *QueryDataSet) GroupByTag(key string) map[string]*QueryDataSet {
+	groups := groupColumns(len(s.Paths), s.Tags, key)
+	ret := make(map[string]*QueryDataSet, len(groups))
+	for value, columns := range groups {
+		ret[value] = s.selectColumns(columns)
+	}
+	return ret
+}
+
+func (s *QueryDataSet) selectColumns(columns []int) *QueryDataSet {
+	ret := &QueryDataSet{}
+	for _, column := range columns {
+		ret.Paths = append(ret.Paths, s.Paths[column])
+		if s.Tags != nil {
+			ret.Tags = append(ret.Tags, columnTags(s.Tags, column))
+		}
+		if column < len(s.Types) {
+			ret.Types = append(ret.Types, s.Types[column])
+		}
+	}
+	for i, row := range s.Values {
+		values := make([]interface{}, len(columns))
+		isEmpty := true
+		for j, column := range columns {
+			if column < len(row) && row[column] != nil {
+				values[j] = row[column]
+				isEmpty = false
+			}
+		}
+		if isEmpty {
+			continue
+		}
+		if i < len(s.Timestamps) {
+			ret.Timestamps = append(ret.Timestamps, s.Timestamps[i])
+		}
+		ret.Values = append(ret.Values, values)
+	}
+	return ret
+}
+
+func (s *AggregateQueryDataSet) ColumnName(index int) string {
+	return formatColumn(s.Paths[index], columnTags(s.Tags, index))
+}
+
+func (s *AggregateQueryDataSet) FilterByTags(filter map[string]string) *AggregateQueryDataSet {
+	return s.selectColumns(filterColumns(len(s.Paths), s.Tags, filter))
+}
+
+func (s *AggregateQueryDataSet) GroupByTag(key string) map[string]*AggregateQueryDataSet {
+	groups := groupColumns(len(s.Paths), s.Tags, key)
+	ret := make(map[string]*AggregateQueryDataSet, len(groups))
+	for value, columns := range groups {
+		ret[value] = s.selectColumns(columns)
+	}
+	return ret
+}
+
+func (s *AggregateQueryDataSet) selectColumns(columns []int) *AggregateQueryDataSet {
+	ret := &AggregateQueryDataSet{AggregateType: s.AggregateType}
+	for _, column := range columns {
+		ret.Paths = append(ret.Paths, s.Paths[column])
+		if s.Tags != nil {
+			ret.Tags = append(ret.Tags, columnTags(s.Tags, column))
+		}
+		if column < len(s.Types) {
+			ret.Types = append(ret.Types, s.Types[column])
+		}
+		// FIRST、LAST 等聚合会为每个序列返回一个时间戳
+		if column < len(s.Timestamps) {
+			ret.Timestamps = append(ret.Timestamps, s.Timestamps[column])
+		}
+		if column < len(s.Values) {
+			ret.Values = append(ret.Values, s.Values[column])
+		}
+	}
+	return ret
+}
+
+func (s *StreamDataSet) ColumnName(index int) string {
+	return formatColumn(s.columns[index], columnTags(s.tags, index))
+}
+
+// ColumnsByTags 返回标签与 filter 匹配的列的下标
+func (s *StreamDataSet) ColumnsByTags(filter map[string]string) []int {
+	var columns []int
+	for _, column := range filterColumns(len(s.columns), s.tags, filter) {
+		// 时间戳列没有标签，不参与筛选
+		if column == streamTimeColumn(s.columns, s.types) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// ColumnsByTag 按标签 key 的值对列分组，返回每组列的下标
+func (s *StreamDataSet) ColumnsByTag(key string) map[string][]int {
+	timeColumn := streamTimeColumn(s.columns, s.types)
+	groups := groupColumns(len(s.columns), s.tags, key)
+	for value, columns := range groups {
+		var ret []int
+		for _, column := range columns {
+			if column != timeColumn {
+				ret = append(ret, column)
+			}
+		}
+		if len(ret) == 0 {
+			delete(groups, value)
+		} else {
+			groups[value] = ret
+		}
+	}
+	return groups
+}
+
+func FilterTimeSeriesByTags(timeSeries []TimeSeries, filter map[string]string) []TimeSeries {
+	var ret []TimeSeries
+	for _, ts := range timeSeries {
+		if MatchTags(ts.tags, filter) {
+			ret = append(ret, ts)
+		}
+	}
+	return ret
+}
+
+func GroupTimeSeriesByTag(timeSeries []TimeSeries, key string) map[string][]TimeSeries {
+	ret := make(map[string][]TimeSeries)
+	for _, ts := range timeSeries {
+		value := ts.tags[key]
+		ret[value] = append(ret[value], ts)
+	}
+	return ret
+}
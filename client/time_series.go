@@ -5,6 +5,7 @@ import "github.com/thulab/iginx-client-go/rpc"
 type TimeSeries struct {
 	path     string
 	dataType rpc.DataType
+	tags     map[string]string
 }
 
 func NewTimeSeries(path string, dataType rpc.DataType) TimeSeries {
@@ -14,6 +15,14 @@ func NewTimeSeries(path string, dataType rpc.DataType) TimeSeries {
 	}
 }
 
+func NewTimeSeriesWithTags(path string, dataType rpc.DataType, tags map[string]string) TimeSeries {
+	return TimeSeries{
+		path:     path,
+		dataType: dataType,
+		tags:     tags,
+	}
+}
+
 func (ts *TimeSeries) GetPath() string {
 	return ts.path
 }
@@ -22,6 +31,13 @@ func (ts *TimeSeries) GetType() rpc.DataType {
 	return ts.dataType
 }
 
+func (ts *TimeSeries) GetTags() map[string]string {
+	return ts.tags
+}
+
 func (ts *TimeSeries) ToString() string {
+	if len(ts.tags) > 0 {
+		return "Path: " + ts.path + ", Tags: " + tagsKey(ts.tags) + ", Type: " + ts.dataType.String()
+	}
 	return "Path: " + ts.path + ", Type: " + ts.dataType.String()
 }
@@ -0,0 +1,402 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+const (
+	DefaultJobPollInterval    = 200 * time.Millisecond
+	DefaultJobMaxPollInterval = 5 * time.Second
+)
+
+var (
+	ErrJobFailed  = errors.New("transform job failed")
+	ErrJobClosed  = errors.New("transform job is closed")
+	ErrJobUnknown = errors.New("transform job is unknown")
+)
+
+// TransformTask 是 Transform 作业中的一个任务，可以是 IginX SQL 任务或 Python 任务
+type TransformTask struct {
+	info *rpc.TaskInfo
+}
+
+// NewSQLTask 创建执行一组 IginX SQL 的任务，默认为批处理
+func NewSQLTask(sqlList ...string) *TransformTask {
+	return &TransformTask{info: &rpc.TaskInfo{
+		TaskType:     rpc.TaskType_IginX,
+		DataFlowType: rpc.DataFlowType_Batch,
+		SqlList:      sqlList,
+	}}
+}
+
+// NewPythonTask 创建执行已注册的 Python 任务的任务，默认为批处理
+func NewPythonTask(pyTaskName string) *TransformTask {
+	return &TransformTask{info: &rpc.TaskInfo{
+		TaskType:     rpc.TaskType_Python,
+		DataFlowType: rpc.DataFlowType_Batch,
+		PyTaskName:   &pyTaskName,
+	}}
+}
+
+func (t *TransformTask) Batch() *TransformTask {
+	t.info.DataFlowType = rpc.DataFlowType_Batch
+	return t
+}
+
+func (t *TransformTask) Stream() *TransformTask {
+	t.info.DataFlowType = rpc.DataFlowType_Stream
+	return t
+}
+
+// WithTimeout 设置任务的超时时间，精度为毫秒
+func (t *TransformTask) WithTimeout(timeout time.Duration) *TransformTask {
+	ms := int64(timeout / time.Millisecond)
+	t.info.Timeout = &ms
+	return t
+}
+
+func (t *TransformTask) TaskInfo() *rpc.TaskInfo {
+	return t.info
+}
+
+func (t *TransformTask) validate() error {
+	switch t.info.TaskType {
+	case rpc.TaskType_IginX:
+		if len(t.info.SqlList) == 0 {
+			return errors.New("sql task should have at least one statement")
+		}
+	case rpc.TaskType_Python:
+		if t.info.GetPyTaskName() == "" {
+			return errors.New("python task should have a task name")
+		}
+	default:
+		return errors.New("unknown task type " + t.info.TaskType.String())
+	}
+	return nil
+}
+
+// TransformJob 是由一组按顺序执行的任务组成的作业，默认将结果输出到日志
+type TransformJob struct {
+	tasks      []*TransformTask
+	exportType rpc.ExportType
+	fileName   string
+}
+
+func NewTransformJob(tasks ...*TransformTask) *TransformJob {
+	return &TransformJob{
+		tasks:      tasks,
+		exportType: rpc.ExportType_Log,
+	}
+}
+
+func (j *TransformJob) AddTask(task *TransformTask) *TransformJob {
+	j.tasks = append(j.tasks, task)
+	return j
+}
+
+func (j *TransformJob) ExportToLog() *TransformJob {
+	j.exportType = rpc.ExportType_Log
+	j.fileName = ""
+	return j
+}
+
+// ExportToFile 将结果输出到 IginX 节点上的文件
+func (j *TransformJob) ExportToFile(fileName string) *TransformJob {
+	j.exportType = rpc.ExportType_File
+	j.fileName = fileName
+	return j
+}
+
+// ExportToIginX 将结果写回 IginX
+func (j *TransformJob) ExportToIginX() *TransformJob {
+	j.exportType = rpc.ExportType_IginX
+	j.fileName = ""
+	return j
+}
+
+func (j *TransformJob) newRequest() (*rpc.CommitTransformJobReq, error) {
+	if len(j.tasks) == 0 {
+		return nil, errors.New("transform job should have at least one task")
+	}
+	req := &rpc.CommitTransformJobReq{
+		ExportType: j.exportType,
+	}
+	for i, task := range j.tasks {
+		if task == nil {
+			return nil, errors.New("task should not be nil")
+		}
+		if err := task.validate(); err != nil {
+			return nil, errors.New("task " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		req.TaskList = append(req.TaskList, task.info)
+	}
+	if j.exportType == rpc.ExportType_File {
+		if j.fileName == "" {
+			return nil, errors.New("file name should be set when exporting to file")
+		}
+		req.FileName = &j.fileName
+	}
+	return req, nil
+}
+
+type jobClient interface {
+	QueryTransformJobStatusContext(ctx context.Context, jobId int64) (rpc.JobState, error)
+	CancelTransformJobContext(ctx context.Context, jobId int64) error
+}
+
+// TransformJobHandle 是已提交的作业，用于查询状态、等待完成和取消
+type TransformJobHandle struct {
+	Id int64
+
+	// Wait 轮询状态的初始间隔，此后每次翻倍，最多为 MaxPollInterval。为 0 时使用 DefaultJobPollInterval 和 DefaultJobMaxPollInterval
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+
+	client jobClient
+}
+
+func newTransformJobHandle(client jobClient, jobId int64) *TransformJobHandle {
+	return &TransformJobHandle{
+		Id:              jobId,
+		PollInterval:    DefaultJobPollInterval,
+		MaxPollInterval: DefaultJobMaxPollInterval,
+		client:          client,
+	}
+}
+
+func (h *TransformJobHandle) Status() (rpc.JobState, error) {
+	return h.StatusContext(context.Background())
+}
+
+func (h *TransformJobHandle) StatusContext(ctx context.Context) (rpc.JobState, error) {
+	return h.client.QueryTransformJobStatusContext(ctx, h.Id)
+}
+
+// Wait 轮询作业状态直到作业结束，作业失败时返回 ErrJobFailed，被取消时返回 ErrJobClosed，
+// 服务端找不到作业时返回 ErrJobUnknown
+func (h *TransformJobHandle) Wait(ctx context.Context) (rpc.JobState, error) {
+	policy := RetryPolicy{Backoff: h.PollInterval, MaxBackoff: h.MaxPollInterval}
+	if policy.Backoff <= 0 {
+		policy.Backoff = DefaultJobPollInterval
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultJobMaxPollInterval
+	}
+	for retry := 0; ; retry++ {
+		state, err := h.StatusContext(ctx)
+		if err != nil {
+			return state, err
+		}
+		switch state {
+		case rpc.JobState_JOB_FINISHED:
+			return state, nil
+		case rpc.JobState_JOB_FAILED:
+			return state, ErrJobFailed
+		case rpc.JobState_JOB_CLOSED:
+			return state, ErrJobClosed
+		case rpc.JobState_JOB_UNKNOWN:
+			return state, ErrJobUnknown
+		}
+
+		timer := time.NewTimer(policy.backoff(retry))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return state, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (h *TransformJobHandle) Cancel() error {
+	return h.CancelContext(context.Background())
+}
+
+func (h *TransformJobHandle) CancelContext(ctx context.Context) error {
+	return h.client.CancelTransformJobContext(ctx, h.Id)
+}
+
+func (s *Session) CommitTransformJob(job *TransformJob) (*TransformJobHandle, error) {
+	return s.CommitTransformJobContext(context.Background(), job)
+}
+
+func (s *Session) CommitTransformJobContext(ctx context.Context, job *TransformJob) (*TransformJobHandle, error) {
+	req, err := job.newRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *rpc.CommitTransformJobResp
+	err = s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.CommitTransformJob(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New("commit transform job resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	return newTransformJobHandle(s, resp.GetJobId()), nil
+}
+
+// GetTransformJob 返回已有作业的句柄
+func (s *Session) GetTransformJob(jobId int64) *TransformJobHandle {
+	return newTransformJobHandle(s, jobId)
+}
+
+func (s *Session) QueryTransformJobStatus(jobId int64) (rpc.JobState, error) {
+	return s.QueryTransformJobStatusContext(context.Background(), jobId)
+}
+
+func (s *Session) QueryTransformJobStatusContext(ctx context.Context, jobId int64) (rpc.JobState, error) {
+	req := rpc.QueryTransformJobStatusReq{
+		SessionId: s.sessionId,
+		JobId:     jobId,
+	}
+
+	var resp *rpc.QueryTransformJobStatusResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.QueryTransformJobStatus(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return rpc.JobState_JOB_UNKNOWN, err
+	} else if resp == nil {
+		return rpc.JobState_JOB_UNKNOWN, errors.New("query transform job status resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return rpc.JobState_JOB_UNKNOWN, err
+	}
+
+	return resp.GetJobState(), nil
+}
+
+// ShowEligibleJob 返回处于指定状态的作业
+func (s *Session) ShowEligibleJob(state rpc.JobState) ([]int64, error) {
+	return s.ShowEligibleJobContext(context.Background(), state)
+}
+
+func (s *Session) ShowEligibleJobContext(ctx context.Context, state rpc.JobState) ([]int64, error) {
+	req := rpc.ShowEligibleJobReq{
+		SessionId: s.sessionId,
+		JobState:  state,
+	}
+
+	var resp *rpc.ShowEligibleJobResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.ShowEligibleJob(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New("show eligible job resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetJobIdList(), nil
+}
+
+func (s *Session) CancelTransformJob(jobId int64) error {
+	return s.CancelTransformJobContext(context.Background(), jobId)
+}
+
+func (s *Session) CancelTransformJobContext(ctx context.Context, jobId int64) error {
+	req := rpc.CancelTransformJobReq{
+		SessionId: s.sessionId,
+		JobId:     jobId,
+	}
+
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.CancelTransformJob(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.verifyStatus(status)
+}
+
+func (p *SessionPool) CommitTransformJob(job *TransformJob) (*TransformJobHandle, error) {
+	return p.CommitTransformJobContext(context.Background(), job)
+}
+
+// CommitTransformJobContext 提交作业，返回的句柄通过连接池查询状态和取消作业
+func (p *SessionPool) CommitTransformJobContext(ctx context.Context, job *TransformJob) (*TransformJobHandle, error) {
+	var jobId int64
+	err := p.do(ctx, func(session *Session) error {
+		handle, err := session.CommitTransformJobContext(ctx, job)
+		if err != nil {
+			return err
+		}
+		jobId = handle.Id
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newTransformJobHandle(p, jobId), nil
+}
+
+func (p *SessionPool) GetTransformJob(jobId int64) *TransformJobHandle {
+	return newTransformJobHandle(p, jobId)
+}
+
+func (p *SessionPool) QueryTransformJobStatus(jobId int64) (rpc.JobState, error) {
+	return p.QueryTransformJobStatusContext(context.Background(), jobId)
+}
+
+func (p *SessionPool) QueryTransformJobStatusContext(ctx context.Context, jobId int64) (rpc.JobState, error) {
+	state := rpc.JobState_JOB_UNKNOWN
+	err := p.do(ctx, func(session *Session) (err error) {
+		state, err = session.QueryTransformJobStatusContext(ctx, jobId)
+		return err
+	})
+	return state, err
+}
+
+func (p *SessionPool) ShowEligibleJob(state rpc.JobState) ([]int64, error) {
+	return p.ShowEligibleJobContext(context.Background(), state)
+}
+
+func (p *SessionPool) ShowEligibleJobContext(ctx context.Context, state rpc.JobState) ([]int64, error) {
+	var ret []int64
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ShowEligibleJobContext(ctx, state)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) CancelTransformJob(jobId int64) error {
+	return p.CancelTransformJobContext(context.Background(), jobId)
+}
+
+func (p *SessionPool) CancelTransformJobContext(ctx context.Context, jobId int64) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.CancelTransformJobContext(ctx, jobId)
+	})
+}
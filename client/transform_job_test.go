@@ -0,0 +1,75 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// jobStates 依次返回 states 中的状态，最后一个状态重复返回
+type jobStates struct {
+	states []rpc.JobState
+	polls  int
+}
+
+func (j *jobStates) QueryTransformJobStatusContext(ctx context.Context, jobId int64) (rpc.JobState, error) {
+	state := j.states[len(j.states)-1]
+	if j.polls < len(j.states) {
+		state = j.states[j.polls]
+	}
+	j.polls++
+	return state, nil
+}
+
+func (j *jobStates) CancelTransformJobContext(ctx context.Context, jobId int64) error {
+	return nil
+}
+
+func TestTransformJobWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		states  []rpc.JobState
+		want    rpc.JobState
+		wantErr error
+	}{
+		{"finished", []rpc.JobState{rpc.JobState_JOB_CREATED, rpc.JobState_JOB_RUNNING, rpc.JobState_JOB_FINISHED}, rpc.JobState_JOB_FINISHED, nil},
+		{"failed", []rpc.JobState{rpc.JobState_JOB_RUNNING, rpc.JobState_JOB_FAILING, rpc.JobState_JOB_FAILED}, rpc.JobState_JOB_FAILED, client.ErrJobFailed},
+		{"closed", []rpc.JobState{rpc.JobState_JOB_CLOSING, rpc.JobState_JOB_CLOSED}, rpc.JobState_JOB_CLOSED, client.ErrJobClosed},
+		{"unknown", []rpc.JobState{rpc.JobState_JOB_UNKNOWN}, rpc.JobState_JOB_UNKNOWN, client.ErrJobUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := &jobStates{states: tt.states}
+			handle := client.NewTransformJobHandle(states, 1)
+			handle.PollInterval = time.Millisecond
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			state, err := handle.Wait(ctx)
+			if state != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait() = %v, %v, want %v, %v", state, err, tt.want, tt.wantErr)
+			}
+			if states.polls != len(tt.states) {
+				t.Errorf("polls = %d, want %d", states.polls, len(tt.states))
+			}
+		})
+	}
+}
+
+func TestTransformJobWaitZeroPollInterval(t *testing.T) {
+	states := &jobStates{states: []rpc.JobState{rpc.JobState_JOB_RUNNING}}
+	handle := client.NewTransformJobHandle(states, 1)
+	handle.PollInterval, handle.MaxPollInterval = 0, 0
+	// 间隔为 0 时使用默认间隔，而不是不停地轮询
+	ctx, cancel := context.WithTimeout(context.Background(), client.DefaultJobPollInterval/2)
+	defer cancel()
+	if _, err := handle.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if states.polls != 1 {
+		t.Errorf("polls = %d, want 1", states.polls)
+	}
+}
@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// RegisteredTask 是已注册的 UDF 或 Python 任务
+type RegisteredTask struct {
+	Name      string
+	ClassName string
+	FileName  string
+	Ip        string
+	Type      rpc.UDFType
+}
+
+func newRegisteredTask(info *rpc.RegisterTaskInfo) RegisteredTask {
+	return RegisteredTask{
+		Name:      info.GetName(),
+		ClassName: info.GetClassName(),
+		FileName:  info.GetFileName(),
+		Ip:        info.GetIP(),
+		Type:      info.GetType(),
+	}
+}
+
+// RegisterUDF 注册 UDF 或 Python 任务，filePath 为 Python 文件在 IginX 节点上的路径
+func (s *Session) RegisterUDF(name, filePath, className string, udfType rpc.UDFType) error {
+	return s.RegisterUDFContext(context.Background(), name, filePath, className, udfType)
+}
+
+func (s *Session) RegisterUDFContext(ctx context.Context, name, filePath, className string, udfType rpc.UDFType) error {
+	if name == "" || filePath == "" || className == "" {
+		return errors.New("name, filePath and className should not be empty")
+	}
+	req := rpc.RegisterTaskReq{
+		SessionId: s.sessionId,
+		Name:      name,
+		FilePath:  filePath,
+		ClassName: className,
+		Type:      udfType,
+	}
+
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.RegisterTask(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.verifyStatus(status)
+}
+
+func (s *Session) DropUDF(name string) error {
+	return s.DropUDFContext(context.Background(), name)
+}
+
+func (s *Session) DropUDFContext(ctx context.Context, name string) error {
+	req := rpc.DropTaskReq{
+		SessionId: s.sessionId,
+		Name:      name,
+	}
+
+	var status *rpc.Status
+	err := s.call(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		status, err = s.client.DropTask(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.verifyStatus(status)
+}
+
+func (s *Session) ListUDFs() ([]RegisteredTask, error) {
+	return s.ListUDFsContext(context.Background())
+}
+
+func (s *Session) ListUDFsContext(ctx context.Context) ([]RegisteredTask, error) {
+	req := rpc.GetRegisterTaskInfoReq{
+		SessionId: s.sessionId,
+	}
+
+	var resp *rpc.GetRegisterTaskInfoResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.GetRegisterTaskInfo(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New("get register task info resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []RegisteredTask
+	for _, info := range resp.GetRegisterTaskInfoList() {
+		ret = append(ret, newRegisteredTask(info))
+	}
+	return ret, nil
+}
+
+// UDFSpec 描述期望存在的 UDF，可以从 JSON 清单中解析
+type UDFSpec struct {
+	Name      string      `json:"name"`
+	FilePath  string      `json:"filePath"`
+	ClassName string      `json:"className"`
+	Type      rpc.UDFType `json:"type"`
+}
+
+// matches 判断已注册的任务是否与清单一致，服务端只保存文件名，因此只比较 filePath 的文件名部分
+func (spec *UDFSpec) matches(task RegisteredTask) bool {
+	return spec.ClassName == task.ClassName && spec.Type == task.Type && filepath.Base(spec.FilePath) == task.FileName
+}
+
+type UDFSyncOptions struct {
+	// 删除不在清单中的任务
+	Prune bool
+	// 返回 true 的已注册任务不会被删除，例如 IginX 内置的 UDF
+	Keep func(task RegisteredTask) bool
+	// 只计算需要进行的操作而不实际执行
+	DryRun bool
+}
+
+type UDFSyncResult struct {
+	Registered []string
+	// 与清单不一致、被删除后重新注册的任务
+	Replaced  []string
+	Dropped   []string
+	Unchanged []string
+}
+
+// SyncUDFs 使已注册的任务与清单保持一致：注册缺失的任务，重新注册不一致的任务，并在 Prune 时删除多余的任务。
+// 重复执行是幂等的，出错时返回已完成的部分
+func (s *Session) SyncUDFs(ctx context.Context, manifest []UDFSpec, options UDFSyncOptions) (*UDFSyncResult, error) {
+	seen := make(map[string]bool)
+	for _, spec := range manifest {
+		if spec.Name == "" {
+			return nil, errors.New("udf name should not be empty")
+		}
+		if seen[spec.Name] {
+			return nil, errors.New("duplicate udf " + spec.Name + " in manifest")
+		}
+		seen[spec.Name] = true
+	}
+
+	tasks, err := s.ListUDFsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]RegisteredTask)
+	for _, task := range tasks {
+		registered[task.Name] = task
+	}
+
+	result := &UDFSyncResult{}
+	for _, spec := range manifest {
+		task, ok := registered[spec.Name]
+		if ok && spec.matches(task) {
+			result.Unchanged = append(result.Unchanged, spec.Name)
+			continue
+		}
+		if !options.DryRun {
+			if ok {
+				if err := s.DropUDFContext(ctx, spec.Name); err != nil {
+					return result, err
+				}
+			}
+			if err := s.RegisterUDFContext(ctx, spec.Name, spec.FilePath, spec.ClassName, spec.Type); err != nil {
+				return result, err
+			}
+		}
+		if ok {
+			result.Replaced = append(result.Replaced, spec.Name)
+		} else {
+			result.Registered = append(result.Registered, spec.Name)
+		}
+	}
+
+	if options.Prune {
+		var stale []string
+		for name, task := range registered {
+			if seen[name] || (options.Keep != nil && options.Keep(task)) {
+				continue
+			}
+			stale = append(stale, name)
+		}
+		sort.Strings(stale)
+		for _, name := range stale {
+			if !options.DryRun {
+				if err := s.DropUDFContext(ctx, name); err != nil {
+					return result, err
+				}
+			}
+			result.Dropped = append(result.Dropped, name)
+		}
+	}
+	return result, nil
+}
+
+func (p *SessionPool) RegisterUDF(name, filePath, className string, udfType rpc.UDFType) error {
+	return p.RegisterUDFContext(context.Background(), name, filePath, className, udfType)
+}
+
+func (p *SessionPool) RegisterUDFContext(ctx context.Context, name, filePath, className string, udfType rpc.UDFType) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.RegisterUDFContext(ctx, name, filePath, className, udfType)
+	})
+}
+
+func (p *SessionPool) DropUDF(name string) error {
+	return p.DropUDFContext(context.Background(), name)
+}
+
+func (p *SessionPool) DropUDFContext(ctx context.Context, name string) error {
+	return p.do(ctx, func(session *Session) error {
+		return session.DropUDFContext(ctx, name)
+	})
+}
+
+func (p *SessionPool) ListUDFs() ([]RegisteredTask, error) {
+	return p.ListUDFsContext(context.Background())
+}
+
+func (p *SessionPool) ListUDFsContext(ctx context.Context) ([]RegisteredTask, error) {
+	var ret []RegisteredTask
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ListUDFsContext(ctx)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) SyncUDFs(ctx context.Context, manifest []UDFSpec, options UDFSyncOptions) (*UDFSyncResult, error) {
+	var ret *UDFSyncResult
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.SyncUDFs(ctx, manifest, options)
+		return err
+	})
+	return ret, err
+}
@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type User struct {
+	Username string
+	Type     rpc.UserType
+	Auths    []rpc.AuthType
+}
+
+// Can 判断用户是否具有某项权限，管理员具有所有权限
+func (u *User) Can(auth rpc.AuthType) bool {
+	if u.Type == rpc.UserType_Administrator {
+		return true
+	}
+	for _, a := range u.Auths {
+		if a == auth {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) IsAdministrator() bool {
+	return u.Type == rpc.UserType_Administrator
+}
+
+// ListUsers 返回所有用户，需要管理员权限
+func (s *Session) ListUsers() ([]User, error) {
+	return s.ListUsersContext(context.Background())
+}
+
+func (s *Session) ListUsersContext(ctx context.Context) ([]User, error) {
+	return s.getUsers(ctx, nil)
+}
+
+// GetUser 返回指定的用户，用户不存在时返回 ErrUserNotFound
+func (s *Session) GetUser(username string) (*User, error) {
+	return s.GetUserContext(context.Background(), username)
+}
+
+func (s *Session) GetUserContext(ctx context.Context, username string) (*User, error) {
+	users, err := s.getUsers(ctx, []string{username})
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].Username == username {
+			return &users[i], nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (s *Session) getUsers(ctx context.Context, usernames []string) ([]User, error) {
+	req := rpc.GetUserReq{
+		SessionId: s.sessionId,
+		Usernames: usernames,
+	}
+
+	var resp *rpc.GetUserResp
+	err := s.retryableCall(ctx, func(ctx context.Context) (err error) {
+		req.SessionId = s.sessionId
+		resp, err = s.client.GetUser(ctx, &req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	} else if resp == nil {
+		return nil, errors.New("get user resp is nil")
+	}
+
+	err = s.verifyStatus(resp.GetStatus())
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []User
+	for i, username := range resp.GetUsernames() {
+		user := User{Username: username}
+		if i < len(resp.GetUserTypes()) {
+			user.Type = resp.GetUserTypes()[i]
+		}
+		if i < len(resp.GetAuths()) {
+			user.Auths = resp.GetAuths()[i]
+		}
+		ret = append(ret, user)
+	}
+	return ret, nil
+}
+
+// CurrentUserCan 判断当前会话的用户是否具有某项权限
+func (s *Session) CurrentUserCan(auth rpc.AuthType) (bool, error) {
+	return s.CurrentUserCanContext(context.Background(), auth)
+}
+
+func (s *Session) CurrentUserCanContext(ctx context.Context, auth rpc.AuthType) (bool, error) {
+	user, err := s.GetUserContext(ctx, s.username)
+	if err != nil {
+		return false, err
+	}
+	return user.Can(auth), nil
+}
+
+// UserSpec 描述期望存在的用户，Password 只在创建用户或 UserReconcileOptions.UpdatePasswords 为 true 时使用。
+// 已有的管理员不会被修改
+type UserSpec struct {
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Auths    []rpc.AuthType `json:"auths"`
+}
+
+type UserReconcileOptions struct {
+	// 删除不在配置中的普通用户，管理员和当前用户不会被删除
+	Prune bool
+	// 服务端无法读取密码，为 true 时总是将已有用户的密码更新为配置中的密码
+	UpdatePasswords bool
+	// 只计算需要进行的操作而不实际执行
+	DryRun bool
+}
+
+type UserReconcileResult struct {
+	Created   []string
+	Updated   []string
+	Deleted   []string
+	Unchanged []string
+}
+
+// ReconcileUsers 使用户及其权限与配置保持一致：创建缺失的用户，更新权限不一致的用户，并在 Prune 时删除多余的用户。
+// 重复执行是幂等的，出错时返回已完成的部分
+func (s *Session) ReconcileUsers(ctx context.Context, desired []UserSpec, options UserReconcileOptions) (*UserReconcileResult, error) {
+	seen := make(map[string]bool)
+	for _, spec := range desired {
+		if spec.Username == "" {
+			return nil, errors.New("username should not be empty")
+		}
+		if seen[spec.Username] {
+			return nil, errors.New("duplicate user " + spec.Username + " in config")
+		}
+		seen[spec.Username] = true
+	}
+
+	users, err := s.ListUsersContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]User)
+	for _, user := range users {
+		existing[user.Username] = user
+	}
+
+	result := &UserReconcileResult{}
+	for _, spec := range desired {
+		user, ok := existing[spec.Username]
+		if !ok {
+			if !options.DryRun {
+				if err := s.AddUserContext(ctx, spec.Username, spec.Password, spec.Auths); err != nil {
+					return result, err
+				}
+			}
+			result.Created = append(result.Created, spec.Username)
+			continue
+		}
+
+		// 管理员的权限和密码不由配置管理
+		if user.IsAdministrator() || (sameAuthSet(user.Auths, spec.Auths) && !options.UpdatePasswords) {
+			result.Unchanged = append(result.Unchanged, spec.Username)
+			continue
+		}
+		if !options.DryRun {
+			var password *string
+			if options.UpdatePasswords {
+				password = &spec.Password
+			}
+			// 为 nil 时不修改权限，期望的权限为空时需要发送空列表才能撤销所有权限
+			auths := spec.Auths
+			if auths == nil {
+				auths = []rpc.AuthType{}
+			}
+			if err := s.updateUser(ctx, spec.Username, password, auths); err != nil {
+				return result, err
+			}
+		}
+		result.Updated = append(result.Updated, spec.Username)
+	}
+
+	if options.Prune {
+		var stale []string
+		for username, user := range existing {
+			if seen[username] || user.IsAdministrator() || username == s.username {
+				continue
+			}
+			stale = append(stale, username)
+		}
+		sort.Strings(stale)
+		for _, username := range stale {
+			if !options.DryRun {
+				if err := s.DeleteUserContext(ctx, username); err != nil {
+					return result, err
+				}
+			}
+			result.Deleted = append(result.Deleted, username)
+		}
+	}
+	return result, nil
+}
+
+func sameAuthSet(a, b []rpc.AuthType) bool {
+	set := make(map[rpc.AuthType]bool)
+	for _, auth := range a {
+		set[auth] = true
+	}
+	other := make(map[rpc.AuthType]bool)
+	for _, auth := range b {
+		if !set[auth] {
+			return false
+		}
+		other[auth] = true
+	}
+	return len(set) == len(other)
+}
+
+func (p *SessionPool) ListUsers() ([]User, error) {
+	return p.ListUsersContext(context.Background())
+}
+
+func (p *SessionPool) ListUsersContext(ctx context.Context) ([]User, error) {
+	var ret []User
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ListUsersContext(ctx)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) GetUser(username string) (*User, error) {
+	return p.GetUserContext(context.Background(), username)
+}
+
+func (p *SessionPool) GetUserContext(ctx context.Context, username string) (*User, error) {
+	var ret *User
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.GetUserContext(ctx, username)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) CurrentUserCan(auth rpc.AuthType) (bool, error) {
+	return p.CurrentUserCanContext(context.Background(), auth)
+}
+
+func (p *SessionPool) CurrentUserCanContext(ctx context.Context, auth rpc.AuthType) (bool, error) {
+	var ret bool
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.CurrentUserCanContext(ctx, auth)
+		return err
+	})
+	return ret, err
+}
+
+func (p *SessionPool) ReconcileUsers(ctx context.Context, desired []UserSpec, options UserReconcileOptions) (*UserReconcileResult, error) {
+	var ret *UserReconcileResult
+	err := p.do(ctx, func(session *Session) (err error) {
+		ret, err = session.ReconcileUsers(ctx, desired, options)
+		return err
+	})
+	return ret, err
+}
@@ -0,0 +1,80 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func TestReconcileUsers(t *testing.T) {
+	tests := []struct {
+		name      string
+		desired   []client.UserSpec
+		options   client.UserReconcileOptions
+		want      client.UserReconcileResult
+		wantAuths []rpc.AuthType
+	}{
+		{
+			name:      "revoke all auths with nil",
+			desired:   []client.UserSpec{{Username: "alice"}},
+			want:      client.UserReconcileResult{Updated: []string{"alice"}},
+			wantAuths: []rpc.AuthType{},
+		},
+		{
+			name:      "revoke all auths with an empty list",
+			desired:   []client.UserSpec{{Username: "alice", Auths: []rpc.AuthType{}}},
+			want:      client.UserReconcileResult{Updated: []string{"alice"}},
+			wantAuths: []rpc.AuthType{},
+		},
+		{
+			name:      "same auths",
+			desired:   []client.UserSpec{{Username: "alice", Auths: []rpc.AuthType{rpc.AuthType_Write, rpc.AuthType_Read}}},
+			want:      client.UserReconcileResult{Unchanged: []string{"alice"}},
+			wantAuths: []rpc.AuthType{rpc.AuthType_Read, rpc.AuthType_Write},
+		},
+		{
+			name: "administrator is never updated",
+			desired: []client.UserSpec{
+				{Username: client.DefaultUsername, Password: "changed"},
+				{Username: "alice", Password: "changed", Auths: []rpc.AuthType{rpc.AuthType_Read, rpc.AuthType_Write}},
+			},
+			options:   client.UserReconcileOptions{UpdatePasswords: true},
+			want:      client.UserReconcileResult{Updated: []string{"alice"}, Unchanged: []string{client.DefaultUsername}},
+			wantAuths: []rpc.AuthType{rpc.AuthType_Read, rpc.AuthType_Write},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t)
+			server.AddUser("alice", "alice", rpc.AuthType_Read, rpc.AuthType_Write)
+			session := openSession(t, server)
+			ctx := context.Background()
+
+			result, err := session.ReconcileUsers(ctx, tt.desired, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*result, tt.want) {
+				t.Errorf("result = %+v, want %+v", *result, tt.want)
+			}
+			user, err := session.GetUserContext(ctx, "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(user.Auths) != fmt.Sprint(tt.wantAuths) {
+				t.Errorf("auths = %v, want %v", user.Auths, tt.wantAuths)
+			}
+
+			// 管理员的密码没有被修改
+			admin, err := server.OpenSession()
+			if err != nil {
+				t.Fatalf("open session as administrator: %v", err)
+			}
+			_ = admin.Close()
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/csvio"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// runExport 执行 export 子命令：iginx-cli [flags] export [-o file] [-tag-rows] <statement>
+func runExport(session *client.Session, fetchSize int32, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	var (
+		output     = flags.String("o", "", "output file, stdout by default")
+		tagRows    = flags.Bool("tag-rows", false, "write tags as rows after the header instead of in column names")
+		timeLayout = flags.String("time-layout", "", "Go time layout of the time column, integer timestamps by default")
+		timeUnit   = flags.Duration("time-unit", time.Millisecond, "unit of timestamps")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: iginx-cli [flags] export [options] <statement>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("statement is required")
+	}
+	statement := strings.Join(flags.Args(), " ")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	dataSet, err := session.ExecuteQueryWithFetchSizeContext(ctx, statement, fetchSize)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			_ = dataSet.Close()
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	buffered := bufio.NewWriter(w)
+	rows, err := csvio.ExportStream(buffered, dataSet, csvio.ExportOptions{
+		TagRows:    *tagRows,
+		TimeLayout: *timeLayout,
+		TimeUnit:   *timeUnit,
+	})
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(os.Stderr, "exported %d rows to %s\n", rows, *output)
+	}
+	return nil
+}
+
+// runImport 执行 import 子命令：iginx-cli [flags] import [-prefix p] [-types a=LONG,b=DOUBLE] [file]
+func runImport(session *client.Session, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	var (
+		prefix      = flags.String("prefix", "", "prefix of the paths")
+		types       = flags.String("types", "", "comma separated column=TYPE list, other columns are inferred from the first batch with values")
+		batchSize   = flags.Int("batch", csvio.DefaultBatchSize, "rows per insert")
+		timeLayout  = flags.String("time-layout", "", "Go time layout of the time column, integer timestamps by default")
+		timeUnit    = flags.Duration("time-unit", time.Millisecond, "unit of timestamps")
+		skipInvalid = flags.Bool("skip-invalid", false, "skip and report invalid rows instead of stopping")
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: iginx-cli [flags] import [options] [file]")
+		fmt.Fprintln(flags.Output(), "Reads the CSV from stdin when file is omitted or is -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return errors.New("at most one file is allowed")
+	}
+	options := csvio.ImportOptions{
+		Prefix:          *prefix,
+		BatchSize:       *batchSize,
+		TimeLayout:      *timeLayout,
+		TimeUnit:        *timeUnit,
+		SkipInvalidRows: *skipInvalid,
+	}
+	if *types != "" {
+		options.Types = make(map[string]rpc.DataType)
+		for _, item := range strings.Split(*types, ",") {
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid type %q, should be column=TYPE", item)
+			}
+			dataType, err := rpc.DataTypeFromString(strings.ToUpper(parts[1]))
+			if err != nil {
+				return fmt.Errorf("invalid type %q", parts[1])
+			}
+			options.Types[parts[0]] = dataType
+		}
+	}
+
+	var r io.Reader = os.Stdin
+	if name := flags.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := csvio.Import(ctx, session, bufio.NewReader(r), options)
+	if result != nil {
+		for _, rowErr := range result.Errors {
+			fmt.Fprintln(os.Stderr, "skipped", rowErr)
+		}
+		fmt.Fprintf(os.Stderr, "imported %d rows, %d points, skipped %d rows\n", result.Rows, result.Points, len(result.Errors))
+	}
+	return err
+}
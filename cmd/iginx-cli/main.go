@@ -0,0 +1,141 @@
+// iginx-cli 是基于 client.Session 的 IginX 交互式命令行：
+//
+//	iginx-cli -h 127.0.0.1 -p 6888 -u root -pw root
+//	iginx-cli -e "SHOW TIME SERIES; SELECT * FROM a WHERE TIME >= 0 AND TIME < 10" -format csv
+//	iginx-cli export -o data.csv "SELECT * FROM a"
+//	iginx-cli import -prefix b data.csv
+//
+// 语句以分号结束，可以跨多行输入。以反斜杠开头的元命令不需要分号，输入 \help 查看所有元命令。
+// 标准输入不是终端时，从标准输入读取语句并以非交互方式执行。
+// export 和 import 子命令在 CSV 和 IginX 之间导出导入数据，CSV 的格式见 csvio 包的说明
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/thulab/iginx-client-go/client"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		host        = flag.String("h", "127.0.0.1", "IginX host")
+		port        = flag.String("p", "6888", "IginX port")
+		username    = flag.String("u", client.DefaultUsername, "username")
+		password    = flag.String("pw", client.DefaultPassword, "password")
+		endpoints   = flag.String("endpoints", "", "comma separated host:port list for failover, overrides -h and -p")
+		execute     = flag.String("e", "", "execute the statements and exit")
+		format      = flag.String("format", formatTable, "output format: table, csv or json")
+		fetchSize   = flag.Int("fetch-size", 1000, "fetch size of SELECT statements")
+		timeout     = flag.Duration("timeout", 0, "socket timeout, 0 means no timeout")
+		historyFile = flag.String("history", defaultHistoryFile(), "history file, empty to disable")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: iginx-cli [flags] [export|import [options] args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var command string
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+		if command != "export" && command != "import" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", command)
+			flag.Usage()
+			return 2
+		}
+	}
+
+	if !isFormat(*format) {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if *fetchSize <= 0 {
+		fmt.Fprintln(os.Stderr, "fetch size should be positive")
+		return 2
+	}
+
+	var opts []client.SessionOption
+	if *timeout > 0 {
+		opts = append(opts, client.WithConnectTimeout(*timeout), client.WithSocketTimeout(*timeout))
+	}
+	var session *client.Session
+	if *endpoints != "" {
+		session = client.NewSessionWithEndpoints(strings.Split(*endpoints, ","), *username, *password, opts...)
+	} else {
+		session = client.NewSessionWithOptions(*host, *port, append(opts, client.WithUser(*username, *password))...)
+	}
+	if err := session.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, "connect failed:", err)
+		return 1
+	}
+	defer session.Close()
+
+	if command != "" {
+		var err error
+		if command == "export" {
+			err = runExport(session, int32(*fetchSize), flag.Args()[1:])
+		} else {
+			err = runImport(session, flag.Args()[1:])
+		}
+		if err == flag.ErrHelp {
+			return 0
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 1
+		}
+		return 0
+	}
+
+	sh := &shell{
+		session:   session,
+		out:       os.Stdout,
+		errOut:    os.Stderr,
+		format:    *format,
+		fetchSize: int32(*fetchSize),
+		history:   &history{},
+	}
+
+	if *execute != "" || !isTerminal(os.Stdin) {
+		var input io.Reader = os.Stdin
+		if *execute != "" {
+			input = strings.NewReader(*execute)
+		}
+		if err := sh.runScript(input); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	sh.interactive = true
+	sh.history = newHistory(*historyFile)
+	fmt.Fprintf(sh.out, "Connected to IginX at %s, type \\help for help\n", strings.Join(session.GetEndpoints(), ","))
+	sh.runInteractive(os.Stdin)
+	_ = sh.history.close()
+	fmt.Fprintln(sh.out, "Bye")
+	return 0
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".iginx_cli_history")
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+const (
+	formatTable = "table"
+	formatCSV   = "csv"
+	formatJSON  = "json"
+)
+
+func isFormat(format string) bool {
+	return format == formatTable || format == formatCSV || format == formatJSON
+}
+
+// resultWriter 按行输出结果，close 之前的输出可能被缓存
+type resultWriter interface {
+	writeHeader(columns []string) error
+	writeRow(values []interface{}) error
+	close() error
+}
+
+func newResultWriter(format string, w io.Writer) resultWriter {
+	switch format {
+	case formatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}
+	case formatJSON:
+		return &jsonWriter{w: w}
+	}
+	return &tableWriter{w: w}
+}
+
+// tableWriter 需要知道每一列的宽度，因此会缓存所有行
+type tableWriter struct {
+	w       io.Writer
+	columns []string
+	rows    [][]string
+}
+
+func (t *tableWriter) writeHeader(columns []string) error {
+	t.columns = columns
+	return nil
+}
+
+func (t *tableWriter) writeRow(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, value := range values {
+		if value == nil {
+			row[i] = "null"
+		} else {
+			row[i] = formatValue(value)
+		}
+	}
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+func (t *tableWriter) close() error {
+	widths := make([]int, len(t.columns))
+	for i, column := range t.columns {
+		widths[i] = utf8.RuneCountInString(column)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); i < len(widths) && n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	separator := func() {
+		b.WriteString("+")
+		for _, width := range widths {
+			b.WriteString(strings.Repeat("-", width+2))
+			b.WriteString("+")
+		}
+		b.WriteString("\n")
+	}
+	line := func(cells []string) {
+		b.WriteString("|")
+		for i, width := range widths {
+			var cell string
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			b.WriteString(" ")
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(cell)+1))
+			b.WriteString("|")
+		}
+		b.WriteString("\n")
+	}
+	separator()
+	line(t.columns)
+	separator()
+	for _, row := range t.rows {
+		line(row)
+	}
+	if len(t.rows) > 0 {
+		separator()
+	}
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// csvWriter 将空值输出为空字符串
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) writeHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) writeRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		if value != nil {
+			record[i] = formatValue(value)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter 输出一个 JSON 数组，每行为一个以列名为键的对象，键的顺序与列的顺序一致
+type jsonWriter struct {
+	w       io.Writer
+	columns []string
+	rows    int
+}
+
+func (j *jsonWriter) writeHeader(columns []string) error {
+	j.columns = columns
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonWriter) writeRow(values []interface{}) error {
+	var b strings.Builder
+	if j.rows > 0 {
+		b.WriteString(",")
+	}
+	b.WriteString("\n  {")
+	for i, value := range values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		key, err := json.Marshal(j.columns[i])
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(jsonValue(value))
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteString(": ")
+		b.Write(data)
+	}
+	b.WriteString("}")
+	j.rows++
+	_, err := io.WriteString(j.w, b.String())
+	return err
+}
+
+func (j *jsonWriter) close() error {
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// jsonValue 将 JSON 无法表示的值转换为字符串
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return formatValue(v)
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return formatValue(v)
+		}
+	case []byte:
+		return string(v)
+	}
+	return value
+}
+
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + tags[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeStream 边拉取边输出流式查询的结果，返回输出的行数
+func writeStream(ctx context.Context, w resultWriter, dataSet *client.StreamDataSet) (int, error) {
+	defer dataSet.Close()
+
+	columns := make([]string, len(dataSet.GetColumns()))
+	for i := range columns {
+		columns[i] = dataSet.ColumnName(i)
+	}
+	if err := w.writeHeader(columns); err != nil {
+		return 0, err
+	}
+	rows := 0
+	for dataSet.HasMoreContext(ctx) {
+		if err := w.writeRow(dataSet.NextRowContext(ctx)); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	err := dataSet.Err()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if closeErr := w.close(); err == nil {
+		err = closeErr
+	}
+	return rows, err
+}
+
+// table 是非流式结果转换后的表格
+type table struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+func (t *table) write(w resultWriter) (int, error) {
+	if err := w.writeHeader(t.columns); err != nil {
+		return 0, err
+	}
+	for _, row := range t.rows {
+		if err := w.writeRow(row); err != nil {
+			return 0, err
+		}
+	}
+	return len(t.rows), w.close()
+}
+
+// sqlDataSetTable 将 SQLDataSet 转换为表格，没有结果的语句返回 nil
+func sqlDataSetTable(dataSet *client.SQLDataSet) *table {
+	switch dataSet.Type {
+	case rpc.SqlType_Query:
+		if dataSet.GetAggregateQueryDataSet() != nil {
+			return aggregateTable(dataSet.GetAggregateQueryDataSet())
+		}
+		return queryTable(dataSet.GetQueryDataSet())
+	case rpc.SqlType_GetReplicaNum:
+		return &table{columns: []string{"replica num"}, rows: [][]interface{}{{dataSet.GetReplicaNum()}}}
+	case rpc.SqlType_CountPoints:
+		return &table{columns: []string{"points num"}, rows: [][]interface{}{{dataSet.GetPointsNum()}}}
+	case rpc.SqlType_ShowTimeSeries:
+		var timeSeries []client.TimeSeries
+		for _, ts := range dataSet.GetTimeSeries() {
+			timeSeries = append(timeSeries, *ts)
+		}
+		return timeSeriesTable(timeSeries)
+	case rpc.SqlType_ShowClusterInfo:
+		return clusterInfoTable(dataSet.GetClusterInfo())
+	case rpc.SqlType_ShowRegisterTask:
+		t := &table{columns: []string{"name", "class name", "file name", "ip", "type"}}
+		for _, task := range dataSet.GetRegisteredTasks() {
+			t.rows = append(t.rows, []interface{}{task.Name, task.ClassName, task.FileName, task.Ip, task.Type.String()})
+		}
+		return t
+	case rpc.SqlType_CommitTransformJob:
+		return &table{columns: []string{"job id"}, rows: [][]interface{}{{dataSet.GetJobId()}}}
+	case rpc.SqlType_ShowJobStatus:
+		return &table{columns: []string{"job id", "state"}, rows: [][]interface{}{{dataSet.GetJobId(), dataSet.GetJobState().String()}}}
+	case rpc.SqlType_ShowEligibleJob:
+		t := &table{columns: []string{"job id"}}
+		for _, jobId := range dataSet.GetJobIdList() {
+			t.rows = append(t.rows, []interface{}{jobId})
+		}
+		return t
+	}
+	return nil
+}
+
+func queryTable(dataSet *client.QueryDataSet) *table {
+	t := &table{columns: []string{"key"}}
+	for i := range dataSet.Paths {
+		t.columns = append(t.columns, dataSet.ColumnName(i))
+	}
+	for i, timestamp := range dataSet.Timestamps {
+		t.rows = append(t.rows, append([]interface{}{timestamp}, dataSet.Values[i]...))
+	}
+	return t
+}
+
+// aggregateTable 在一行中输出每个序列的聚合值，FIRST 和 LAST 等带有时间戳的聚合每个序列输出一行
+func aggregateTable(dataSet *client.AggregateQueryDataSet) *table {
+	aggregateType := dataSet.AggregateType.String()
+	if dataSet.Timestamps != nil {
+		t := &table{columns: []string{"key", "path", aggregateType}}
+		for i := range dataSet.Paths {
+			t.rows = append(t.rows, []interface{}{dataSet.Timestamps[i], dataSet.ColumnName(i), dataSet.Values[i]})
+		}
+		return t
+	}
+	t := &table{rows: [][]interface{}{dataSet.Values}}
+	for i := range dataSet.Paths {
+		t.columns = append(t.columns, aggregateType+"("+dataSet.ColumnName(i)+")")
+	}
+	return t
+}
+
+func timeSeriesTable(timeSeries []client.TimeSeries) *table {
+	t := &table{columns: []string{"path", "tags", "type"}}
+	for _, ts := range timeSeries {
+		t.rows = append(t.rows, []interface{}{ts.GetPath(), formatTags(ts.GetTags()), ts.GetType().String()})
+	}
+	return t
+}
+
+func clusterInfoTable(info *client.ClusterInfo) *table {
+	t := &table{columns: []string{"role", "id", "ip", "port", "type"}}
+	for _, iginx := range info.GetIginxInfos() {
+		t.rows = append(t.rows, []interface{}{"iginx", iginx.Id, iginx.Ip, iginx.Port, nil})
+	}
+	for _, engine := range info.GetStorageEngineInfos() {
+		t.rows = append(t.rows, []interface{}{"storage engine", engine.Id, engine.Ip, engine.Port, engine.Type})
+	}
+	if info.IsUseLocalMetaStorage() {
+		t.rows = append(t.rows, []interface{}{"local meta storage", nil, info.GetLocalMetaStoragePath(), nil, nil})
+	}
+	for _, meta := range info.GetMetaStorageInfos() {
+		t.rows = append(t.rows, []interface{}{"meta storage", nil, meta.Ip, meta.Port, meta.Type})
+	}
+	return t
+}
+
+func usersTable(users []client.User) *table {
+	t := &table{columns: []string{"username", "type", "auths"}}
+	for _, user := range users {
+		auths := make([]string, len(user.Auths))
+		for i, auth := range user.Auths {
+			auths[i] = auth.String()
+		}
+		t.rows = append(t.rows, []interface{}{user.Username, user.Type.String(), strings.Join(auths, ",")})
+	}
+	return t
+}
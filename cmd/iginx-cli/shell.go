@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+)
+
+const (
+	prompt             = "IginX> "
+	continuationPrompt = "    -> "
+)
+
+var errQuit = errors.New("quit")
+
+type shell struct {
+	session     *client.Session
+	out         io.Writer
+	errOut      io.Writer
+	format      string
+	fetchSize   int32
+	interactive bool
+	history     *history
+}
+
+// runScript 依次执行 r 中的语句，遇到第一个错误时停止
+func (s *shell) runScript(r io.Reader) error {
+	var sp splitter
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		for _, command := range s.feed(&sp, scanner.Text()) {
+			if err := s.run(command); err == errQuit {
+				return nil
+			} else if err != nil {
+				fmt.Fprintln(s.errOut, "error:", err)
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(s.errOut, "error:", err)
+		return err
+	}
+	// 最后一条语句可以省略分号
+	if rest := sp.rest(); rest != "" {
+		if err := s.run(rest); err != nil && err != errQuit {
+			fmt.Fprintln(s.errOut, "error:", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runInteractive 逐行读取输入，错误不会结束会话，输入 \q 或 EOF 时退出
+func (s *shell) runInteractive(r io.Reader) {
+	var sp splitter
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for {
+		if sp.pending() {
+			fmt.Fprint(s.out, continuationPrompt)
+		} else {
+			fmt.Fprint(s.out, prompt)
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+		for _, command := range s.feed(&sp, scanner.Text()) {
+			if !strings.HasPrefix(command, `\`) {
+				s.history.add(command)
+			}
+			if err := s.run(command); err == errQuit {
+				return
+			} else if err != nil {
+				fmt.Fprintln(s.errOut, "error:", err)
+			}
+		}
+	}
+}
+
+// feed 将一行输入交给 splitter。以反斜杠开头的行作为元命令，并结束之前未以分号结束的语句；
+// 没有未完成的语句时，exit 和 quit 等同于 \q
+func (s *shell) feed(sp *splitter, line string) []string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, `\`) && !sp.inQuote() {
+		var commands []string
+		if rest := sp.rest(); rest != "" {
+			commands = append(commands, rest)
+		}
+		return append(commands, strings.TrimSuffix(trimmed, ";"))
+	}
+	if !sp.pending() {
+		switch strings.ToLower(strings.TrimSuffix(trimmed, ";")) {
+		case "exit", "quit":
+			return []string{`\q`}
+		}
+	}
+	return sp.feed(line)
+}
+
+func (s *shell) run(command string) error {
+	if strings.HasPrefix(command, `\`) {
+		return s.runMeta(command)
+	}
+	return s.execute(command)
+}
+
+// execute 执行一条语句，SELECT 语句使用流式查询，执行期间按 Ctrl-C 会取消该语句
+func (s *shell) execute(statement string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	var rows int
+	var err error
+	if isSelect(statement) {
+		var dataSet *client.StreamDataSet
+		dataSet, err = s.session.ExecuteQueryWithFetchSizeContext(ctx, statement, s.fetchSize)
+		if err != nil {
+			return err
+		}
+		rows, err = writeStream(ctx, newResultWriter(s.format, s.out), dataSet)
+	} else {
+		var dataSet *client.SQLDataSet
+		dataSet, err = s.session.ExecuteSQLContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+		t := sqlDataSetTable(dataSet)
+		if t == nil {
+			s.done(-1, start)
+			return nil
+		}
+		rows, err = t.write(newResultWriter(s.format, s.out))
+	}
+	if err != nil {
+		return err
+	}
+	s.done(rows, start)
+	return nil
+}
+
+// done 在交互模式下输出行数和耗时，rows 为负数时表示语句没有结果
+func (s *shell) done(rows int, start time.Time) {
+	if !s.interactive {
+		return
+	}
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case rows < 0:
+		fmt.Fprintf(s.out, "OK (%s)\n", elapsed)
+	case rows == 1:
+		fmt.Fprintf(s.out, "1 row (%s)\n", elapsed)
+	default:
+		fmt.Fprintf(s.out, "%d rows (%s)\n", rows, elapsed)
+	}
+}
+
+func (s *shell) write(t *table, start time.Time) error {
+	rows, err := t.write(newResultWriter(s.format, s.out))
+	if err != nil {
+		return err
+	}
+	s.done(rows, start)
+	return nil
+}
+
+const metaHelp = `Statements end with ';' and may span multiple lines. SELECT statements are streamed.
+
+Meta commands:
+  \help               show this help
+  \q, \quit           exit the shell
+  \cluster            show cluster info
+  \replicas           show the replica number
+  \series [prefix]    list time series, optionally only those under prefix
+  \users              list users
+  \user <name>        show a user
+  \format [format]    show or set the output format: table, csv or json
+  \history [n]        show the last n statements of the history, 20 by default
+  \run <n>            execute statement n of the history again
+`
+
+func (s *shell) runMeta(command string) error {
+	fields := strings.Fields(command)
+	name, args := fields[0], fields[1:]
+	start := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch name {
+	case `\help`, `\h`, `\?`:
+		_, err := io.WriteString(s.out, metaHelp)
+		return err
+	case `\q`, `\quit`:
+		return errQuit
+	case `\cluster`:
+		info, err := s.session.GetClusterInfoContext(ctx)
+		if err != nil {
+			return err
+		}
+		return s.write(clusterInfoTable(info), start)
+	case `\replicas`:
+		replicaNum, err := s.session.GetReplicaNumContext(ctx)
+		if err != nil {
+			return err
+		}
+		return s.write(&table{columns: []string{"replica num"}, rows: [][]interface{}{{replicaNum}}}, start)
+	case `\series`:
+		timeSeries, err := s.session.ListTimeSeriesContext(ctx)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			var matched []client.TimeSeries
+			for _, ts := range timeSeries {
+				if path := ts.GetPath(); path == args[0] || strings.HasPrefix(path, args[0]+".") {
+					matched = append(matched, ts)
+				}
+			}
+			timeSeries = matched
+		}
+		return s.write(timeSeriesTable(timeSeries), start)
+	case `\users`:
+		users, err := s.session.ListUsersContext(ctx)
+		if err != nil {
+			return err
+		}
+		return s.write(usersTable(users), start)
+	case `\user`:
+		if len(args) != 1 {
+			return errors.New(`usage: \user <name>`)
+		}
+		user, err := s.session.GetUserContext(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return s.write(usersTable([]client.User{*user}), start)
+	case `\format`:
+		if len(args) == 0 {
+			fmt.Fprintln(s.out, s.format)
+			return nil
+		}
+		if !isFormat(args[0]) {
+			return fmt.Errorf("unknown format %q", args[0])
+		}
+		s.format = args[0]
+		return nil
+	case `\history`:
+		n := 20
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 {
+				return errors.New(`usage: \history [n]`)
+			}
+		}
+		s.history.print(s.out, n)
+		return nil
+	case `\run`:
+		if len(args) != 1 {
+			return errors.New(`usage: \run <n>`)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.New(`usage: \run <n>`)
+		}
+		statement, ok := s.history.get(n)
+		if !ok {
+			return fmt.Errorf("no statement %d in history", n)
+		}
+		fmt.Fprintln(s.out, statement)
+		s.history.add(statement)
+		return s.execute(statement)
+	}
+	return fmt.Errorf("unknown command %s, type \\help for help", name)
+}
+
+func isSelect(statement string) bool {
+	fields := strings.Fields(statement)
+	return len(fields) > 0 && strings.EqualFold(fields[0], "select")
+}
+
+// splitter 将多行输入按分号切分为语句，引号中的分号不作为分隔符
+type splitter struct {
+	buf     strings.Builder
+	quote   rune
+	escaped bool
+}
+
+// feed 输入一行，返回这一行中结束的语句
+func (sp *splitter) feed(line string) []string {
+	var statements []string
+	if sp.buf.Len() > 0 {
+		sp.buf.WriteByte('\n')
+	}
+	for _, c := range line {
+		switch {
+		case sp.escaped:
+			sp.escaped = false
+		case sp.quote != 0:
+			if c == '\\' {
+				sp.escaped = true
+			} else if c == sp.quote {
+				sp.quote = 0
+			}
+		case c == '"' || c == '\'':
+			sp.quote = c
+		case c == ';':
+			if statement := strings.TrimSpace(sp.buf.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			sp.buf.Reset()
+			continue
+		}
+		sp.buf.WriteRune(c)
+	}
+	if strings.TrimSpace(sp.buf.String()) == "" {
+		sp.buf.Reset()
+	}
+	return statements
+}
+
+// pending 判断是否有未以分号结束的语句
+func (sp *splitter) pending() bool {
+	return sp.buf.Len() > 0
+}
+
+// inQuote 判断未完成的语句是否停在引号中
+func (sp *splitter) inQuote() bool {
+	return sp.quote != 0
+}
+
+// rest 返回并清空未以分号结束的语句
+func (sp *splitter) rest() string {
+	rest := strings.TrimSpace(sp.buf.String())
+	sp.buf.Reset()
+	sp.quote, sp.escaped = 0, false
+	return rest
+}
+
+// history 记录交互模式下执行的语句，语句按输入原样保存。历史文件中每条语句占一行，换行和反斜杠被转义
+type history struct {
+	entries []string
+	file    *os.File
+}
+
+const maxHistory = 1000
+
+func newHistory(path string) *history {
+	h := &history{}
+	if path == "" {
+		return h
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				h.entries = append(h.entries, unescapeHistory(line))
+			}
+		}
+		if len(h.entries) > maxHistory {
+			h.entries = h.entries[len(h.entries)-maxHistory:]
+		}
+	}
+	// 历史文件不可写时只在内存中记录
+	h.file, _ = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	return h
+}
+
+func (h *history) add(statement string) {
+	if n := len(h.entries); n > 0 && h.entries[n-1] == statement {
+		return
+	}
+	h.entries = append(h.entries, statement)
+	if h.file != nil {
+		_, _ = h.file.WriteString(escapeHistory(statement) + "\n")
+	}
+}
+
+// get 返回编号为 n 的语句，编号从 1 开始
+func (h *history) get(n int) (string, bool) {
+	if n < 1 || n > len(h.entries) {
+		return "", false
+	}
+	return h.entries[n-1], true
+}
+
+func (h *history) print(w io.Writer, n int) {
+	start := len(h.entries) - n
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(h.entries); i++ {
+		// 多行语句的后续行与第一行对齐
+		fmt.Fprintf(w, "%5d  %s\n", i+1, strings.ReplaceAll(h.entries[i], "\n", "\n       "))
+	}
+}
+
+var historyEscaper = strings.NewReplacer(`\`, `\\`, "\r", `\r`, "\n", `\n`)
+
+func escapeHistory(statement string) string {
+	return historyEscaper.Replace(statement)
+}
+
+var historyUnescapes = map[byte]byte{'\\': '\\', 'r': '\r', 'n': '\n'}
+
+// unescapeHistory 还原 escapeHistory 转义的字符，其他反斜杠保持不变
+func unescapeHistory(line string) string {
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			if ch, ok := historyUnescapes[line[i+1]]; ok {
+				sb.WriteByte(ch)
+				i++
+				continue
+			}
+		}
+		sb.WriteByte(line[i])
+	}
+	return sb.String()
+}
+
+func (h *history) close() error {
+	if h.file == nil {
+		return nil
+	}
+	return h.file.Close()
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryKeepsStatements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	statements := []string{
+		`SELECT a FROM test WHERE b = "two  spaces"`,
+		"SELECT a\n  FROM test\n  WHERE b = \"line\nbreak\"",
+		`INSERT INTO test (TIMESTAMP, a) VALUES (1, "back\\slash \n")`,
+	}
+
+	h := newHistory(path)
+	for _, statement := range statements {
+		h.add(statement)
+	}
+	if err := h.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开后从历史文件中读取的语句与输入相同
+	reopened := newHistory(path)
+	defer reopened.close()
+	for _, h := range []*history{h, reopened} {
+		for i, want := range statements {
+			if got, ok := h.get(i + 1); !ok || got != want {
+				t.Errorf("get(%d) = %q, want %q", i+1, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package csvio 在 IginX 与 CSV 之间导入导出数据。
+//
+// CSV 的第一行为表头，第一列为时间戳，其余每列对应一个序列。导出时可以在表头之后为每个标签键输出一行标签，
+// 这一行的第一格为 TagRowPrefix 加标签键，其余格为对应序列的标签值，导入时会识别这些行。空值对应空的单元格：
+//
+//	key,a.b,a.b,a.c
+//	tag:host,h1,h2,
+//	1,1.5,2.5,x
+//	2,,3.5,
+package csvio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+)
+
+const (
+	// DefaultTimeColumn 是导出时时间戳列的列名
+	DefaultTimeColumn = "key"
+	// TagRowPrefix 是标签行第一格的前缀
+	TagRowPrefix = "tag:"
+)
+
+type ExportOptions struct {
+	// 时间戳列的列名，为空时使用 DefaultTimeColumn
+	TimeColumn string
+	// 为 true 时在表头之后输出标签行，否则带标签的序列以 a.b{k=v} 的形式作为列名。结果中没有时间戳列时忽略
+	TagRows bool
+	// 设置后按该格式输出时间，时间戳的单位为 TimeUnit
+	TimeLayout string
+	// 时间戳的单位，为 0 时为毫秒
+	TimeUnit time.Duration
+	// 列分隔符，为 0 时为逗号
+	Comma rune
+}
+
+// ExportQueryDataSet 将 Query、DownSampleQuery 或 LastQuery 的结果写为 CSV
+func ExportQueryDataSet(w io.Writer, dataSet *client.QueryDataSet, options ExportOptions) error {
+	e := newExporter(w, options)
+	if err := e.writeHeader(dataSet.Paths, dataSet.Tags, true); err != nil {
+		return err
+	}
+	for i, timestamp := range dataSet.Timestamps {
+		if err := e.writeRow(timestamp, true, dataSet.Values[i]); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// ExportStream 边拉取边将 ExecuteQuery 的结果写为 CSV，返回写入的行数，写完后关闭 dataSet。
+// 结果中没有时间戳列时，CSV 中也不包含时间戳列
+func ExportStream(w io.Writer, dataSet *client.StreamDataSet, options ExportOptions) (int64, error) {
+	defer dataSet.Close()
+
+	columns := dataSet.ColumnsByTags(nil)
+	paths := make([]string, len(columns))
+	var tags []map[string]string
+	if dataSet.GetTags() != nil {
+		tags = make([]map[string]string, len(columns))
+	}
+	for i, column := range columns {
+		paths[i] = dataSet.GetColumns()[column]
+		if tags != nil && column < len(dataSet.GetTags()) {
+			tags[i] = dataSet.GetTags()[column]
+		}
+	}
+	hasTime := len(columns) < len(dataSet.GetColumns())
+
+	e := newExporter(w, options)
+	if err := e.writeHeader(paths, tags, hasTime); err != nil {
+		return 0, err
+	}
+	var rows int64
+	for dataSet.Next() {
+		if err := e.writeRow(dataSet.Timestamp(), hasTime, dataSet.Row()); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	if err := dataSet.Err(); err != nil {
+		return rows, err
+	}
+	return rows, e.flush()
+}
+
+type exporter struct {
+	w       *csv.Writer
+	options ExportOptions
+	record  []string
+}
+
+func newExporter(w io.Writer, options ExportOptions) *exporter {
+	if options.TimeColumn == "" {
+		options.TimeColumn = DefaultTimeColumn
+	}
+	if options.TimeUnit <= 0 {
+		options.TimeUnit = time.Millisecond
+	}
+	writer := csv.NewWriter(w)
+	if options.Comma != 0 {
+		writer.Comma = options.Comma
+	}
+	return &exporter{w: writer, options: options}
+}
+
+// writeHeader 写入表头和标签行，没有时间戳列时无法区分标签行和数据行，标签总是写在列名中
+func (e *exporter) writeHeader(paths []string, tags []map[string]string, hasTime bool) error {
+	tagRows := e.options.TagRows && hasTime
+	var header []string
+	if hasTime {
+		header = append(header, e.options.TimeColumn)
+	}
+	for i, path := range paths {
+		if !tagRows && i < len(tags) && len(tags[i]) > 0 {
+			path += formatTags(tags[i])
+		}
+		header = append(header, path)
+	}
+	if err := e.w.Write(header); err != nil {
+		return err
+	}
+	if !tagRows {
+		return nil
+	}
+
+	for _, key := range tagKeys(tags) {
+		row := []string{TagRowPrefix + key}
+		for i := range paths {
+			var value string
+			if i < len(tags) {
+				value = tags[i][key]
+			}
+			row = append(row, value)
+		}
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) writeRow(timestamp int64, hasTime bool, values []interface{}) error {
+	e.record = e.record[:0]
+	if hasTime {
+		e.record = append(e.record, e.formatTime(timestamp))
+	}
+	for _, value := range values {
+		e.record = append(e.record, formatValue(value))
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *exporter) formatTime(timestamp int64) string {
+	if e.options.TimeLayout == "" {
+		return strconv.FormatInt(timestamp, 10)
+	}
+	nanos := timestamp * int64(e.options.TimeUnit)
+	return time.Unix(0, nanos).Format(e.options.TimeLayout)
+}
+
+func (e *exporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(value)
+}
+
+func formatTags(tags map[string]string) string {
+	ret := "{"
+	for i, key := range sortedKeys(tags) {
+		if i > 0 {
+			ret += ","
+		}
+		ret += key + "=" + tags[key]
+	}
+	return ret + "}"
+}
+
+// tagKeys 返回所有序列的标签键，按字典序排列
+func tagKeys(tags []map[string]string) []string {
+	set := make(map[string]string)
+	for _, m := range tags {
+		for k := range m {
+			set[k] = ""
+		}
+	}
+	return sortedKeys(set)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
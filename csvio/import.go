@@ -0,0 +1,420 @@
+package csvio
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+const DefaultBatchSize = 10000
+
+type ImportOptions struct {
+	// 设置后作为每一列路径的前缀
+	Prefix string
+	// 指定列的数据类型，键为表头中的列名或不含前缀的路径。未指定的列根据第一批有值的数据推断类型：
+	// 全为整数时为 LONG，全为数字时为 DOUBLE，全为 true 或 false 时为 BOOLEAN，否则为 BINARY。
+	// 推断的类型在整个导入中不变，IginX 中一个序列只能有一种类型，之后的批次中无法按该类型解析的值会使该行出错，
+	// 例如前一批全为整数、之后出现小数的列。这样的列需要在 Types 中指定类型
+	Types map[string]rpc.DataType
+	// 每次 InsertColumnRecords 写入的最大行数，为 0 时为 DefaultBatchSize
+	BatchSize int
+	// 设置后按该格式解析时间，否则时间为整数时间戳，时间戳的单位为 TimeUnit
+	TimeLayout string
+	// 时间戳的单位，为 0 时为毫秒
+	TimeUnit time.Duration
+	// 列分隔符，为 0 时为逗号
+	Comma rune
+	// 为 true 时跳过无法解析的行并记录在 ImportResult.Errors 中，否则遇到第一个无法解析的行时停止
+	SkipInvalidRows bool
+}
+
+// RowError 是 CSV 中某一行的错误，Line 从 1 开始
+type RowError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %s: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+type ImportResult struct {
+	// 写入的行数和非空的数据点数，值全为空的行也计入行数
+	Rows   int64
+	Points int64
+	// 跳过的行，只在 SkipInvalidRows 为 true 时记录
+	Errors []*RowError
+}
+
+// Import 读取 r 中的 CSV 并通过 InsertColumnRecords 分批写入 IginX，CSV 的格式见包的说明。
+// 返回错误时 ImportResult 中为已经写入的数据，在遇到错误之前读取的行会先被写入
+func Import(ctx context.Context, c client.Client, r io.Reader, options ImportOptions) (*ImportResult, error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.TimeUnit <= 0 {
+		options.TimeUnit = time.Millisecond
+	}
+	reader := csv.NewReader(r)
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv is empty")
+	} else if err != nil {
+		return nil, err
+	}
+	if len(header) < 2 {
+		return nil, errors.New("csv should have a time column and at least one value column")
+	}
+	im := &importer{
+		ctx:     ctx,
+		client:  c,
+		options: options,
+		columns: make([]column, len(header)-1),
+		result:  &ImportResult{},
+	}
+	for i, name := range header[1:] {
+		if err := im.columns[i].parse(name, options); err != nil {
+			return nil, &RowError{Line: 1, Column: name, Err: err}
+		}
+	}
+
+	tagRows := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return im.result, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		// 标签行只能出现在数据行之前
+		if tagRows && strings.HasPrefix(record[0], TagRowPrefix) {
+			if err := im.addTags(line, strings.TrimPrefix(record[0], TagRowPrefix), record[1:]); err != nil {
+				return im.result, err
+			}
+			continue
+		}
+		tagRows = false
+
+		im.rows = append(im.rows, rawRow{line: line, fields: record})
+		if len(im.rows) >= options.BatchSize {
+			if err := im.flush(); err != nil {
+				return im.result, err
+			}
+		}
+	}
+	if err := im.flush(); err != nil {
+		return im.result, err
+	}
+	return im.result, nil
+}
+
+// column 是 CSV 中的一个值列
+type column struct {
+	name     string
+	path     string
+	tags     map[string]string
+	dataType rpc.DataType
+	typed    bool
+	// inferred 为 true 时类型由第一批数据推断，而不是在 Types 中指定
+	inferred bool
+}
+
+// parse 解析形如 a.b 或 a.b{k1=v1,k2=v2} 的列名
+func (c *column) parse(name string, options ImportOptions) error {
+	c.name = name
+	c.path = name
+	if i := strings.IndexByte(name, '{'); i >= 0 {
+		if !strings.HasSuffix(name, "}") {
+			return errors.New("invalid tags in column name")
+		}
+		c.path = name[:i]
+		c.tags = make(map[string]string)
+		if tags := name[i+1 : len(name)-1]; tags != "" {
+			for _, kv := range strings.Split(tags, ",") {
+				parts := strings.SplitN(kv, "=", 2)
+				if len(parts) != 2 || parts[0] == "" {
+					return errors.New("invalid tags in column name")
+				}
+				c.tags[parts[0]] = parts[1]
+			}
+		}
+	}
+	if c.path == "" {
+		return errors.New("empty path")
+	}
+
+	if dataType, ok := options.Types[name]; ok {
+		c.dataType, c.typed = dataType, true
+	} else if dataType, ok := options.Types[c.path]; ok {
+		c.dataType, c.typed = dataType, true
+	}
+	if options.Prefix != "" {
+		c.path = options.Prefix + "." + c.path
+	}
+	return nil
+}
+
+type rawRow struct {
+	line   int
+	fields []string
+}
+
+type importer struct {
+	ctx     context.Context
+	client  client.Client
+	options ImportOptions
+	columns []column
+	rows    []rawRow
+	result  *ImportResult
+}
+
+func (im *importer) addTags(line int, key string, values []string) error {
+	if key == "" {
+		return &RowError{Line: line, Err: errors.New("empty tag key")}
+	}
+	for i, value := range values {
+		if i >= len(im.columns) {
+			return &RowError{Line: line, Err: fmt.Errorf("expect %d fields, but got %d", len(im.columns)+1, len(values)+1)}
+		}
+		if value == "" {
+			continue
+		}
+		if im.columns[i].tags == nil {
+			im.columns[i].tags = make(map[string]string)
+		}
+		im.columns[i].tags[key] = value
+	}
+	return nil
+}
+
+// flush 解析并写入缓存的行，未指定类型且之前没有数据的列在此时推断类型，之后的批次沿用该类型
+func (im *importer) flush() error {
+	rows := im.rows
+	im.rows = nil
+	if len(rows) == 0 {
+		return nil
+	}
+	im.inferTypes(rows)
+
+	timestamps := make([]int64, 0, len(rows))
+	values := make([][]interface{}, 0, len(rows))
+	var rowErr *RowError
+	for _, row := range rows {
+		timestamp, rowValues, err := im.parseRow(row)
+		if err != nil {
+			if !im.options.SkipInvalidRows {
+				rowErr = err
+				break
+			}
+			im.result.Errors = append(im.result.Errors, err)
+			continue
+		}
+		timestamps = append(timestamps, timestamp)
+		values = append(values, rowValues)
+	}
+	if err := im.insert(timestamps, values); err != nil {
+		return err
+	}
+	if rowErr != nil {
+		return rowErr
+	}
+	return nil
+}
+
+func (im *importer) inferTypes(rows []rawRow) {
+	for j := range im.columns {
+		c := &im.columns[j]
+		if c.typed {
+			continue
+		}
+		var dataType rpc.DataType
+		found := false
+		for _, row := range rows {
+			if len(row.fields) != len(im.columns)+1 {
+				continue
+			}
+			field := row.fields[j+1]
+			if field == "" {
+				continue
+			}
+			fieldType := inferType(field)
+			if !found {
+				dataType, found = fieldType, true
+			} else if dataType != fieldType {
+				if isNumber(dataType) && isNumber(fieldType) {
+					dataType = rpc.DataType_DOUBLE
+				} else {
+					dataType = rpc.DataType_BINARY
+				}
+			}
+		}
+		if found {
+			c.dataType, c.typed, c.inferred = dataType, true, true
+		}
+	}
+}
+
+func inferType(field string) rpc.DataType {
+	if _, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return rpc.DataType_LONG
+	}
+	if _, err := strconv.ParseFloat(field, 64); err == nil {
+		return rpc.DataType_DOUBLE
+	}
+	if strings.EqualFold(field, "true") || strings.EqualFold(field, "false") {
+		return rpc.DataType_BOOLEAN
+	}
+	return rpc.DataType_BINARY
+}
+
+func isNumber(dataType rpc.DataType) bool {
+	return dataType == rpc.DataType_LONG || dataType == rpc.DataType_DOUBLE
+}
+
+func (im *importer) parseRow(row rawRow) (int64, []interface{}, *RowError) {
+	if len(row.fields) != len(im.columns)+1 {
+		return 0, nil, &RowError{Line: row.line, Err: fmt.Errorf("expect %d fields, but got %d", len(im.columns)+1, len(row.fields))}
+	}
+	timestamp, err := im.parseTime(row.fields[0])
+	if err != nil {
+		return 0, nil, &RowError{Line: row.line, Column: "time", Err: err}
+	}
+	values := make([]interface{}, len(im.columns))
+	for j, field := range row.fields[1:] {
+		if field == "" {
+			continue
+		}
+		c := &im.columns[j]
+		value, err := parseValue(field, c.dataType)
+		if err != nil {
+			if c.inferred {
+				err = fmt.Errorf("%v, the type %s was inferred from an earlier batch, set it in ImportOptions.Types", err, c.dataType)
+			}
+			return 0, nil, &RowError{Line: row.line, Column: c.name, Err: err}
+		}
+		values[j] = value
+	}
+	return timestamp, values, nil
+}
+
+func (im *importer) parseTime(field string) (int64, error) {
+	if im.options.TimeLayout == "" {
+		return strconv.ParseInt(field, 10, 64)
+	}
+	t, err := time.Parse(im.options.TimeLayout, field)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano() / int64(im.options.TimeUnit), nil
+}
+
+func parseValue(field string, dataType rpc.DataType) (interface{}, error) {
+	switch dataType {
+	case rpc.DataType_BOOLEAN:
+		return strconv.ParseBool(field)
+	case rpc.DataType_INTEGER:
+		v, err := strconv.ParseInt(field, 10, 32)
+		return int32(v), err
+	case rpc.DataType_LONG:
+		return strconv.ParseInt(field, 10, 64)
+	case rpc.DataType_FLOAT:
+		v, err := strconv.ParseFloat(field, 32)
+		return float32(v), err
+	case rpc.DataType_DOUBLE:
+		return strconv.ParseFloat(field, 64)
+	case rpc.DataType_BINARY:
+		return field, nil
+	}
+	return nil, errors.New("unknown data type " + dataType.String())
+}
+
+// insert 按时间戳和路径排好序后写入，整列为空的列不写入
+func (im *importer) insert(timestamps []int64, values [][]interface{}) error {
+	if len(timestamps) == 0 {
+		return nil
+	}
+	rowIndex := make([]int, len(timestamps))
+	for i := range rowIndex {
+		rowIndex[i] = i
+	}
+	sort.SliceStable(rowIndex, func(i, j int) bool {
+		return timestamps[rowIndex[i]] < timestamps[rowIndex[j]]
+	})
+	sortedTimestamps := make([]int64, len(timestamps))
+	for i, index := range rowIndex {
+		sortedTimestamps[i] = timestamps[index]
+	}
+
+	var columnIndex []int
+	for j := range im.columns {
+		for _, row := range values {
+			if row[j] != nil {
+				columnIndex = append(columnIndex, j)
+				break
+			}
+		}
+	}
+	sort.SliceStable(columnIndex, func(i, j int) bool {
+		return im.columns[columnIndex[i]].path < im.columns[columnIndex[j]].path
+	})
+
+	var paths []string
+	var types []rpc.DataType
+	var valueList [][]interface{}
+	var tagsList []map[string]string
+	hasTags := false
+	var points int64
+	for _, j := range columnIndex {
+		c := im.columns[j]
+		columnValues := make([]interface{}, len(rowIndex))
+		for i, index := range rowIndex {
+			columnValues[i] = values[index][j]
+			if columnValues[i] != nil {
+				points++
+			}
+		}
+		paths = append(paths, c.path)
+		types = append(types, c.dataType)
+		valueList = append(valueList, columnValues)
+		tagsList = append(tagsList, c.tags)
+		if len(c.tags) > 0 {
+			hasTags = true
+		}
+	}
+	if !hasTags {
+		tagsList = nil
+	}
+
+	if len(paths) > 0 {
+		if err := im.client.InsertColumnRecordsContext(im.ctx, paths, sortedTimestamps, valueList, types, tagsList); err != nil {
+			return err
+		}
+	}
+	im.result.Rows += int64(len(timestamps))
+	im.result.Points += points
+	return nil
+}
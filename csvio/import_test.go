@@ -0,0 +1,70 @@
+package csvio_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/csvio"
+	"github.com/thulab/iginx-client-go/iginxtest"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+func TestImportInferredTypes(t *testing.T) {
+	// 第一批中 a 全为整数，第二批出现小数
+	const data = "key,a,b\n1,1,x\n2,2,y\n3,1.5,z\n"
+	tests := []struct {
+		name     string
+		types    map[string]rpc.DataType
+		wantLine int
+		want     []rpc.DataType
+	}{
+		{"inferred from the first batch", nil, 4, nil},
+		{"types set", map[string]rpc.DataType{"a": rpc.DataType_DOUBLE}, 0, []rpc.DataType{rpc.DataType_DOUBLE, rpc.DataType_BINARY}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := iginxtest.NewServer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+			session, err := server.OpenSession()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer session.Close()
+
+			result, err := csvio.Import(context.Background(), session, strings.NewReader(data),
+				csvio.ImportOptions{Prefix: "test", Types: tt.types, BatchSize: 2})
+			if tt.wantLine > 0 {
+				var rowErr *csvio.RowError
+				if !errors.As(err, &rowErr) || rowErr.Line != tt.wantLine || rowErr.Column != "a" {
+					t.Fatalf("err = %v, want a RowError at line %d, column a", err, tt.wantLine)
+				}
+				if !strings.Contains(err.Error(), "ImportOptions.Types") {
+					t.Errorf("err = %v, want it to mention ImportOptions.Types", err)
+				}
+				if result.Rows != 2 {
+					t.Errorf("rows = %d, want the first batch to be written", result.Rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Rows != 3 || result.Points != 6 {
+				t.Errorf("result = %+v, want 3 rows and 6 points", result)
+			}
+			dataSet, err := session.Query([]string{"test.*"}, 0, 10, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(dataSet.Types, tt.want) {
+				t.Errorf("types = %v, want %v", dataSet.Types, tt.want)
+			}
+		})
+	}
+}
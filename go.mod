@@ -2,4 +2,9 @@ module github.com/thulab/iginx-client-go
 
 go 1.17
 
-require github.com/apache/thrift v0.16.0
+require (
+	github.com/apache/arrow/go/arrow v0.0.0-20211112161151-bc219186db40
+	github.com/apache/thrift v0.16.0
+)
+
+require golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
@@ -0,0 +1,505 @@
+package iginxtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var _ client.Client = (*MockClient)(nil)
+
+// Call 是 MockClient 记录的一次调用，Method 为不带 Context 后缀的方法名，Args 不包含 context
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// MockClient 是 client.Client 的手写 mock，会记录每一次调用。
+// 带 Context 和不带 Context 的方法共用同一个 XxxFunc，未设置 XxxFunc 时返回零值和 nil 错误
+type MockClient struct {
+	ListTimeSeriesFunc                func(ctx context.Context) ([]client.TimeSeries, error)
+	InsertRowRecordsFunc              func(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedRowRecordsFunc    func(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertColumnRecordsFunc           func(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertNonAlignedColumnRecordsFunc func(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error
+	InsertRecordsFunc                 func(ctx context.Context, records ...*client.Record) error
+	InsertStructsFunc                 func(ctx context.Context, prefix string, items interface{}) error
+	DeleteDataFunc                    func(ctx context.Context, path string, startTime, endTime int64, tagsList map[string][]string) error
+	BatchDeleteDataFunc               func(ctx context.Context, paths []string, startTime, endTime int64, tagsList map[string][]string) error
+	DeleteTimeSeriesFunc              func(ctx context.Context, path string) error
+	BatchDeleteTimeSeriesFunc         func(ctx context.Context, paths []string) error
+	QueryFunc                         func(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*client.QueryDataSet, error)
+	DownSampleQueryFunc               func(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*client.QueryDataSet, error)
+	AggregateQueryFunc                func(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*client.AggregateQueryDataSet, error)
+	LastQueryFunc                     func(ctx context.Context, paths []string, startTime int64, tagList map[string][]string) (*client.QueryDataSet, error)
+	QueryStreamFunc                   func(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string, options client.QueryStreamOptions) (*client.QueryStream, error)
+	CurveMatchFunc                    func(ctx context.Context, paths []string, startTime, endTime int64, curve []float64, unit int64) (*client.CurveMatchResult, error)
+	ExecuteSQLFunc                    func(ctx context.Context, sql string) (*client.SQLDataSet, error)
+	ExecuteQueryFunc                  func(ctx context.Context, statement string) (*client.StreamDataSet, error)
+	ExecuteQueryWithFetchSizeFunc     func(ctx context.Context, statement string, fetchSize int32) (*client.StreamDataSet, error)
+	GetReplicaNumFunc                 func(ctx context.Context) (int32, error)
+	GetClusterInfoFunc                func(ctx context.Context) (*client.ClusterInfo, error)
+	AddStorageEngineFunc              func(ctx context.Context, ip string, port string, engineType string, extra map[string]string) error
+	BatchAddStorageEngineFunc         func(ctx context.Context, engines []*rpc.StorageEngine) error
+	AddUserFunc                       func(ctx context.Context, username string, password string, auths []rpc.AuthType) error
+	DeleteUserFunc                    func(ctx context.Context, username string) error
+	UpdateUserFunc                    func(ctx context.Context, username string, password string, auths []rpc.AuthType) error
+	UpdateUserAuthsFunc               func(ctx context.Context, username string, auths []rpc.AuthType) error
+	GetUserFunc                       func(ctx context.Context, username string) (*client.User, error)
+	ListUsersFunc                     func(ctx context.Context) ([]client.User, error)
+	CurrentUserCanFunc                func(ctx context.Context, auth rpc.AuthType) (bool, error)
+	RegisterUDFFunc                   func(ctx context.Context, name string, filePath string, className string, udfType rpc.UDFType) error
+	DropUDFFunc                       func(ctx context.Context, name string) error
+	ListUDFsFunc                      func(ctx context.Context) ([]client.RegisteredTask, error)
+	CloseFunc                         func() error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (m *MockClient) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, Call{Method: method, Args: args})
+}
+
+// Calls 返回按调用顺序记录的所有调用
+func (m *MockClient) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallsTo 返回对指定方法的调用
+func (m *MockClient) CallsTo(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ret []Call
+	for _, call := range m.calls {
+		if call.Method == method {
+			ret = append(ret, call)
+		}
+	}
+	return ret
+}
+
+// Reset 清空已记录的调用，XxxFunc 保持不变
+func (m *MockClient) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+}
+
+func (m *MockClient) ListTimeSeries() ([]client.TimeSeries, error) {
+	return m.ListTimeSeriesContext(context.Background())
+}
+
+func (m *MockClient) ListTimeSeriesContext(ctx context.Context) ([]client.TimeSeries, error) {
+	m.record("ListTimeSeries")
+	if m.ListTimeSeriesFunc != nil {
+		return m.ListTimeSeriesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) InsertRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return m.InsertRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (m *MockClient) InsertRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	m.record("InsertRowRecords", paths, timestamps, valueList, dataTypeList, tagsList)
+	if m.InsertRowRecordsFunc != nil {
+		return m.InsertRowRecordsFunc(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) InsertNonAlignedRowRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return m.InsertNonAlignedRowRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (m *MockClient) InsertNonAlignedRowRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	m.record("InsertNonAlignedRowRecords", paths, timestamps, valueList, dataTypeList, tagsList)
+	if m.InsertNonAlignedRowRecordsFunc != nil {
+		return m.InsertNonAlignedRowRecordsFunc(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) InsertColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return m.InsertColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (m *MockClient) InsertColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	m.record("InsertColumnRecords", paths, timestamps, valueList, dataTypeList, tagsList)
+	if m.InsertColumnRecordsFunc != nil {
+		return m.InsertColumnRecordsFunc(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) InsertNonAlignedColumnRecords(paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	return m.InsertNonAlignedColumnRecordsContext(context.Background(), paths, timestamps, valueList, dataTypeList, tagsList)
+}
+
+func (m *MockClient) InsertNonAlignedColumnRecordsContext(ctx context.Context, paths []string, timestamps []int64, valueList [][]interface{}, dataTypeList []rpc.DataType, tagsList []map[string]string) error {
+	m.record("InsertNonAlignedColumnRecords", paths, timestamps, valueList, dataTypeList, tagsList)
+	if m.InsertNonAlignedColumnRecordsFunc != nil {
+		return m.InsertNonAlignedColumnRecordsFunc(ctx, paths, timestamps, valueList, dataTypeList, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) InsertRecords(records ...*client.Record) error {
+	return m.InsertRecordsContext(context.Background(), records...)
+}
+
+func (m *MockClient) InsertRecordsContext(ctx context.Context, records ...*client.Record) error {
+	m.record("InsertRecords", records)
+	if m.InsertRecordsFunc != nil {
+		return m.InsertRecordsFunc(ctx, records...)
+	}
+	return nil
+}
+
+func (m *MockClient) InsertStructs(prefix string, items interface{}) error {
+	return m.InsertStructsContext(context.Background(), prefix, items)
+}
+
+func (m *MockClient) InsertStructsContext(ctx context.Context, prefix string, items interface{}) error {
+	m.record("InsertStructs", prefix, items)
+	if m.InsertStructsFunc != nil {
+		return m.InsertStructsFunc(ctx, prefix, items)
+	}
+	return nil
+}
+
+func (m *MockClient) DeleteData(path string, startTime, endTime int64, tagsList map[string][]string) error {
+	return m.DeleteDataContext(context.Background(), path, startTime, endTime, tagsList)
+}
+
+func (m *MockClient) DeleteDataContext(ctx context.Context, path string, startTime, endTime int64, tagsList map[string][]string) error {
+	m.record("DeleteData", path, startTime, endTime, tagsList)
+	if m.DeleteDataFunc != nil {
+		return m.DeleteDataFunc(ctx, path, startTime, endTime, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) BatchDeleteData(paths []string, startTime, endTime int64, tagsList map[string][]string) error {
+	return m.BatchDeleteDataContext(context.Background(), paths, startTime, endTime, tagsList)
+}
+
+func (m *MockClient) BatchDeleteDataContext(ctx context.Context, paths []string, startTime, endTime int64, tagsList map[string][]string) error {
+	m.record("BatchDeleteData", paths, startTime, endTime, tagsList)
+	if m.BatchDeleteDataFunc != nil {
+		return m.BatchDeleteDataFunc(ctx, paths, startTime, endTime, tagsList)
+	}
+	return nil
+}
+
+func (m *MockClient) DeleteTimeSeries(path string) error {
+	return m.DeleteTimeSeriesContext(context.Background(), path)
+}
+
+func (m *MockClient) DeleteTimeSeriesContext(ctx context.Context, path string) error {
+	m.record("DeleteTimeSeries", path)
+	if m.DeleteTimeSeriesFunc != nil {
+		return m.DeleteTimeSeriesFunc(ctx, path)
+	}
+	return nil
+}
+
+func (m *MockClient) BatchDeleteTimeSeries(paths []string) error {
+	return m.BatchDeleteTimeSeriesContext(context.Background(), paths)
+}
+
+func (m *MockClient) BatchDeleteTimeSeriesContext(ctx context.Context, paths []string) error {
+	m.record("BatchDeleteTimeSeries", paths)
+	if m.BatchDeleteTimeSeriesFunc != nil {
+		return m.BatchDeleteTimeSeriesFunc(ctx, paths)
+	}
+	return nil
+}
+
+func (m *MockClient) Query(paths []string, startTime, endTime int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	return m.QueryContext(context.Background(), paths, startTime, endTime, tagList)
+}
+
+func (m *MockClient) QueryContext(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	m.record("Query", paths, startTime, endTime, tagList)
+	if m.QueryFunc != nil {
+		return m.QueryFunc(ctx, paths, startTime, endTime, tagList)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) DownSampleQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	return m.DownSampleQueryContext(context.Background(), paths, startTime, endTime, aggregateType, precision, tagList)
+}
+
+func (m *MockClient) DownSampleQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	m.record("DownSampleQuery", paths, startTime, endTime, aggregateType, precision, tagList)
+	if m.DownSampleQueryFunc != nil {
+		return m.DownSampleQueryFunc(ctx, paths, startTime, endTime, aggregateType, precision, tagList)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) AggregateQuery(paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*client.AggregateQueryDataSet, error) {
+	return m.AggregateQueryContext(context.Background(), paths, startTime, endTime, aggregateType, tagList)
+}
+
+func (m *MockClient) AggregateQueryContext(ctx context.Context, paths []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagList map[string][]string) (*client.AggregateQueryDataSet, error) {
+	m.record("AggregateQuery", paths, startTime, endTime, aggregateType, tagList)
+	if m.AggregateQueryFunc != nil {
+		return m.AggregateQueryFunc(ctx, paths, startTime, endTime, aggregateType, tagList)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) LastQuery(paths []string, startTime int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	return m.LastQueryContext(context.Background(), paths, startTime, tagList)
+}
+
+func (m *MockClient) LastQueryContext(ctx context.Context, paths []string, startTime int64, tagList map[string][]string) (*client.QueryDataSet, error) {
+	m.record("LastQuery", paths, startTime, tagList)
+	if m.LastQueryFunc != nil {
+		return m.LastQueryFunc(ctx, paths, startTime, tagList)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) QueryStream(ctx context.Context, paths []string, startTime, endTime int64, tagList map[string][]string, options client.QueryStreamOptions) (*client.QueryStream, error) {
+	m.record("QueryStream", paths, startTime, endTime, tagList, options)
+	if m.QueryStreamFunc != nil {
+		return m.QueryStreamFunc(ctx, paths, startTime, endTime, tagList, options)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) CurveMatch(paths []string, startTime, endTime int64, curve []float64, unit int64) (*client.CurveMatchResult, error) {
+	return m.CurveMatchContext(context.Background(), paths, startTime, endTime, curve, unit)
+}
+
+func (m *MockClient) CurveMatchContext(ctx context.Context, paths []string, startTime, endTime int64, curve []float64, unit int64) (*client.CurveMatchResult, error) {
+	m.record("CurveMatch", paths, startTime, endTime, curve, unit)
+	if m.CurveMatchFunc != nil {
+		return m.CurveMatchFunc(ctx, paths, startTime, endTime, curve, unit)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) ExecuteSQL(sql string) (*client.SQLDataSet, error) {
+	return m.ExecuteSQLContext(context.Background(), sql)
+}
+
+func (m *MockClient) ExecuteSQLContext(ctx context.Context, sql string) (*client.SQLDataSet, error) {
+	m.record("ExecuteSQL", sql)
+	if m.ExecuteSQLFunc != nil {
+		return m.ExecuteSQLFunc(ctx, sql)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) ExecuteQuery(statement string) (*client.StreamDataSet, error) {
+	return m.ExecuteQueryContext(context.Background(), statement)
+}
+
+func (m *MockClient) ExecuteQueryContext(ctx context.Context, statement string) (*client.StreamDataSet, error) {
+	m.record("ExecuteQuery", statement)
+	if m.ExecuteQueryFunc != nil {
+		return m.ExecuteQueryFunc(ctx, statement)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) ExecuteQueryWithFetchSize(statement string, fetchSize int32) (*client.StreamDataSet, error) {
+	return m.ExecuteQueryWithFetchSizeContext(context.Background(), statement, fetchSize)
+}
+
+func (m *MockClient) ExecuteQueryWithFetchSizeContext(ctx context.Context, statement string, fetchSize int32) (*client.StreamDataSet, error) {
+	m.record("ExecuteQueryWithFetchSize", statement, fetchSize)
+	if m.ExecuteQueryWithFetchSizeFunc != nil {
+		return m.ExecuteQueryWithFetchSizeFunc(ctx, statement, fetchSize)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) GetReplicaNum() (int32, error) {
+	return m.GetReplicaNumContext(context.Background())
+}
+
+func (m *MockClient) GetReplicaNumContext(ctx context.Context) (int32, error) {
+	m.record("GetReplicaNum")
+	if m.GetReplicaNumFunc != nil {
+		return m.GetReplicaNumFunc(ctx)
+	}
+	return 0, nil
+}
+
+func (m *MockClient) GetClusterInfo() (*client.ClusterInfo, error) {
+	return m.GetClusterInfoContext(context.Background())
+}
+
+func (m *MockClient) GetClusterInfoContext(ctx context.Context) (*client.ClusterInfo, error) {
+	m.record("GetClusterInfo")
+	if m.GetClusterInfoFunc != nil {
+		return m.GetClusterInfoFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) AddStorageEngine(ip string, port string, engineType string, extra map[string]string) error {
+	return m.AddStorageEngineContext(context.Background(), ip, port, engineType, extra)
+}
+
+func (m *MockClient) AddStorageEngineContext(ctx context.Context, ip string, port string, engineType string, extra map[string]string) error {
+	m.record("AddStorageEngine", ip, port, engineType, extra)
+	if m.AddStorageEngineFunc != nil {
+		return m.AddStorageEngineFunc(ctx, ip, port, engineType, extra)
+	}
+	return nil
+}
+
+func (m *MockClient) BatchAddStorageEngine(engines []*rpc.StorageEngine) error {
+	return m.BatchAddStorageEngineContext(context.Background(), engines)
+}
+
+func (m *MockClient) BatchAddStorageEngineContext(ctx context.Context, engines []*rpc.StorageEngine) error {
+	m.record("BatchAddStorageEngine", engines)
+	if m.BatchAddStorageEngineFunc != nil {
+		return m.BatchAddStorageEngineFunc(ctx, engines)
+	}
+	return nil
+}
+
+func (m *MockClient) AddUser(username string, password string, auths []rpc.AuthType) error {
+	return m.AddUserContext(context.Background(), username, password, auths)
+}
+
+func (m *MockClient) AddUserContext(ctx context.Context, username string, password string, auths []rpc.AuthType) error {
+	m.record("AddUser", username, password, auths)
+	if m.AddUserFunc != nil {
+		return m.AddUserFunc(ctx, username, password, auths)
+	}
+	return nil
+}
+
+func (m *MockClient) DeleteUser(username string) error {
+	return m.DeleteUserContext(context.Background(), username)
+}
+
+func (m *MockClient) DeleteUserContext(ctx context.Context, username string) error {
+	m.record("DeleteUser", username)
+	if m.DeleteUserFunc != nil {
+		return m.DeleteUserFunc(ctx, username)
+	}
+	return nil
+}
+
+func (m *MockClient) UpdateUser(username string, password string, auths []rpc.AuthType) error {
+	return m.UpdateUserContext(context.Background(), username, password, auths)
+}
+
+func (m *MockClient) UpdateUserContext(ctx context.Context, username string, password string, auths []rpc.AuthType) error {
+	m.record("UpdateUser", username, password, auths)
+	if m.UpdateUserFunc != nil {
+		return m.UpdateUserFunc(ctx, username, password, auths)
+	}
+	return nil
+}
+
+func (m *MockClient) UpdateUserAuths(username string, auths []rpc.AuthType) error {
+	return m.UpdateUserAuthsContext(context.Background(), username, auths)
+}
+
+func (m *MockClient) UpdateUserAuthsContext(ctx context.Context, username string, auths []rpc.AuthType) error {
+	m.record("UpdateUserAuths", username, auths)
+	if m.UpdateUserAuthsFunc != nil {
+		return m.UpdateUserAuthsFunc(ctx, username, auths)
+	}
+	return nil
+}
+
+func (m *MockClient) GetUser(username string) (*client.User, error) {
+	return m.GetUserContext(context.Background(), username)
+}
+
+func (m *MockClient) GetUserContext(ctx context.Context, username string) (*client.User, error) {
+	m.record("GetUser", username)
+	if m.GetUserFunc != nil {
+		return m.GetUserFunc(ctx, username)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) ListUsers() ([]client.User, error) {
+	return m.ListUsersContext(context.Background())
+}
+
+func (m *MockClient) ListUsersContext(ctx context.Context) ([]client.User, error) {
+	m.record("ListUsers")
+	if m.ListUsersFunc != nil {
+		return m.ListUsersFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) CurrentUserCan(auth rpc.AuthType) (bool, error) {
+	return m.CurrentUserCanContext(context.Background(), auth)
+}
+
+func (m *MockClient) CurrentUserCanContext(ctx context.Context, auth rpc.AuthType) (bool, error) {
+	m.record("CurrentUserCan", auth)
+	if m.CurrentUserCanFunc != nil {
+		return m.CurrentUserCanFunc(ctx, auth)
+	}
+	return false, nil
+}
+
+func (m *MockClient) RegisterUDF(name string, filePath string, className string, udfType rpc.UDFType) error {
+	return m.RegisterUDFContext(context.Background(), name, filePath, className, udfType)
+}
+
+func (m *MockClient) RegisterUDFContext(ctx context.Context, name string, filePath string, className string, udfType rpc.UDFType) error {
+	m.record("RegisterUDF", name, filePath, className, udfType)
+	if m.RegisterUDFFunc != nil {
+		return m.RegisterUDFFunc(ctx, name, filePath, className, udfType)
+	}
+	return nil
+}
+
+func (m *MockClient) DropUDF(name string) error {
+	return m.DropUDFContext(context.Background(), name)
+}
+
+func (m *MockClient) DropUDFContext(ctx context.Context, name string) error {
+	m.record("DropUDF", name)
+	if m.DropUDFFunc != nil {
+		return m.DropUDFFunc(ctx, name)
+	}
+	return nil
+}
+
+func (m *MockClient) ListUDFs() ([]client.RegisteredTask, error) {
+	return m.ListUDFsContext(context.Background())
+}
+
+func (m *MockClient) ListUDFsContext(ctx context.Context) ([]client.RegisteredTask, error) {
+	m.record("ListUDFs")
+	if m.ListUDFsFunc != nil {
+		return m.ListUDFsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockClient) Close() error {
+	m.record("Close")
+	if m.CloseFunc != nil {
+		return m.CloseFunc()
+	}
+	return nil
+}
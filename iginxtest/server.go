@@ -0,0 +1,224 @@
+// Package iginxtest 提供一个在进程内运行的 IginX 服务端，数据保存在内存中，用于不依赖 IginX 集群的测试：
+//
+//	server, err := iginxtest.NewServer()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer server.Close()
+//
+//	session, err := server.OpenSession()
+//
+// 支持会话、四种写入方式、原始数据查询、降采样查询、聚合查询、last 查询、删除、查询序列、用户管理以及部分 SQL，
+// UDF、transform 任务和曲线匹配会返回错误状态。Restart 和 SetLatency 可以模拟服务端重启和慢请求
+package iginxtest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var _ rpc.IService = (*service)(nil)
+
+type Server struct {
+	Host string
+	Port string
+
+	server    *thrift.TSimpleServer
+	transport *serverTransport
+	service   *service
+	// done 在服务端停止时关闭，用于中断 SetLatency 设置的等待
+	done chan struct{}
+	// latency 为处理每个请求前的等待时间，单位为纳秒
+	latency int64
+}
+
+// delayedProcessor 在写入每个响应前等待 SetLatency 设置的时间，服务端停止时立即继续
+type delayedProcessor struct {
+	thrift.TProcessor
+	latency *int64
+	done    <-chan struct{}
+}
+
+func (p *delayedProcessor) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	return p.TProcessor.Process(ctx, in, &delayedProtocol{TProtocol: out, processor: p})
+}
+
+func (p *delayedProcessor) wait() {
+	latency := time.Duration(atomic.LoadInt64(p.latency))
+	if latency <= 0 {
+		return
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-p.done:
+	}
+}
+
+// delayedProtocol 在请求已经读取并处理之后、写入响应之前等待
+type delayedProtocol struct {
+	thrift.TProtocol
+	processor *delayedProcessor
+}
+
+func (p *delayedProtocol) WriteMessageBegin(ctx context.Context, name string, typeId thrift.TMessageType, seqid int32) error {
+	p.processor.wait()
+	return p.TProtocol.WriteMessageBegin(ctx, name, typeId, seqid)
+}
+
+// serverTransport 记录已接受的连接，使 Close 不必等待客户端断开
+type serverTransport struct {
+	thrift.TServerTransport
+
+	mu    sync.Mutex
+	conns []thrift.TTransport
+}
+
+func (t *serverTransport) Accept() (thrift.TTransport, error) {
+	conn, err := t.TServerTransport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.conns = append(t.conns, conn)
+	t.mu.Unlock()
+	return conn, nil
+}
+
+func (t *serverTransport) closeConns() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, conn := range t.conns {
+		_ = conn.Close()
+	}
+	t.conns = nil
+}
+
+// NewServer 在 127.0.0.1 的随机端口上启动服务端，使用客户端默认的 socket 传输和二进制协议
+func NewServer() (*Server, error) {
+	s := &Server{service: newService()}
+	if err := s.start("127.0.0.1:0"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Server) start(addr string) error {
+	socket, err := thrift.NewTServerSocket(addr)
+	if err != nil {
+		return err
+	}
+	if err := socket.Listen(); err != nil {
+		return err
+	}
+	host, port, err := net.SplitHostPort(socket.Addr().String())
+	if err != nil {
+		_ = socket.Close()
+		return err
+	}
+
+	s.Host, s.Port = host, port
+	s.transport = &serverTransport{TServerTransport: socket}
+	s.done = make(chan struct{})
+	processor := &delayedProcessor{
+		TProcessor: rpc.NewIServiceProcessor(s.service),
+		latency:    &s.latency,
+		done:       s.done,
+	}
+	s.server = thrift.NewTSimpleServer4(
+		processor,
+		s.transport,
+		thrift.NewTTransportFactory(),
+		thrift.NewTBinaryProtocolFactoryConf(nil),
+	)
+	s.server.SetLogger(thrift.NopLogger)
+	go func() {
+		_ = s.server.Serve()
+	}()
+	return nil
+}
+
+// Addr 返回 host:port 形式的地址
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, s.Port)
+}
+
+// Close 停止服务端并断开所有客户端连接
+func (s *Server) Close() error {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	_ = s.transport.Interrupt()
+	s.transport.closeConns()
+	return s.server.Stop()
+}
+
+// Restart 模拟服务端重启：断开所有客户端连接并在原来的端口上重新启动。数据和用户保留，会话和未关闭的查询失效
+func (s *Server) Restart() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	s.service.mu.Lock()
+	s.service.sessions = make(map[int64]string)
+	s.service.queries = make(map[int64]*streamQuery)
+	s.service.mu.Unlock()
+	return s.start(s.Addr())
+}
+
+// SetLatency 设置服务端处理每个请求前的等待时间，用于测试超时和取消，为 0 时不等待
+func (s *Server) SetLatency(latency time.Duration) {
+	atomic.StoreInt64(&s.latency, int64(latency))
+}
+
+// NewSession 返回使用默认用户连接到该服务端的会话，会话尚未打开
+func (s *Server) NewSession(opts ...client.SessionOption) *client.Session {
+	return client.NewSessionWithOptions(s.Host, s.Port, opts...)
+}
+
+// OpenSession 返回使用默认用户连接到该服务端并已打开的会话
+func (s *Server) OpenSession(opts ...client.SessionOption) (*client.Session, error) {
+	session := s.NewSession(opts...)
+	if err := session.Open(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// PoolConfig 返回连接到该服务端的连接池配置
+func (s *Server) PoolConfig() client.PoolConfig {
+	return client.PoolConfig{
+		Host: s.Host,
+		Port: s.Port,
+	}
+}
+
+// AddUser 直接在服务端添加普通用户，不需要打开会话
+func (s *Server) AddUser(username, password string, auths ...rpc.AuthType) {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+	s.service.users[username] = &user{
+		password: password,
+		userType: rpc.UserType_OrdinaryUser,
+		auths:    auths,
+	}
+}
+
+// PointCount 返回服务端保存的数据点数
+func (s *Server) PointCount() int64 {
+	return s.service.store.countPoints()
+}
+
+// Reset 清空所有数据，会话和用户保持不变
+func (s *Server) Reset() {
+	s.service.store.clear()
+}
@@ -0,0 +1,474 @@
+package iginxtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var errNotSupported = errors.New("not supported by iginxtest")
+
+type user struct {
+	password string
+	userType rpc.UserType
+	auths    []rpc.AuthType
+}
+
+type streamQuery struct {
+	rows    [][]byte
+	bitmaps [][]byte
+}
+
+// service 实现了 rpc.IService，数据保存在内存中
+type service struct {
+	store *store
+
+	mu            sync.Mutex
+	users         map[string]*user
+	sessions      map[int64]string
+	nextSessionId int64
+	queries       map[int64]*streamQuery
+	nextQueryId   int64
+}
+
+func newService() *service {
+	return &service{
+		store: newStore(),
+		users: map[string]*user{
+			client.DefaultUsername: {
+				password: client.DefaultPassword,
+				userType: rpc.UserType_Administrator,
+				auths:    []rpc.AuthType{rpc.AuthType_Read, rpc.AuthType_Write, rpc.AuthType_Admin, rpc.AuthType_Cluster},
+			},
+		},
+		sessions: make(map[int64]string),
+		queries:  make(map[int64]*streamQuery),
+	}
+}
+
+func success() *rpc.Status {
+	return &rpc.Status{Code: client.SuccessCode}
+}
+
+func failure(code int32, err error) *rpc.Status {
+	return &rpc.Status{Code: code, Message: thrift.StringPtr(err.Error())}
+}
+
+func executionError(err error) *rpc.Status {
+	return failure(client.StatementExecutionErrorCode, err)
+}
+
+// checkSession 检查会话是否存在，不存在时返回错误状态
+func (s *service) checkSession(sessionId int64) *rpc.Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[sessionId]; !ok {
+		return failure(client.SystemErrorCode, errors.New("session does not exist"))
+	}
+	return nil
+}
+
+func (s *service) OpenSession(ctx context.Context, req *rpc.OpenSessionReq) (*rpc.OpenSessionResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[req.GetUsername()]
+	if !ok || u.password != req.GetPassword() {
+		return &rpc.OpenSessionResp{Status: failure(client.SystemErrorCode, errors.New("invalid username or password"))}, nil
+	}
+	s.nextSessionId++
+	s.sessions[s.nextSessionId] = req.GetUsername()
+	return &rpc.OpenSessionResp{Status: success(), SessionId: thrift.Int64Ptr(s.nextSessionId)}, nil
+}
+
+func (s *service) CloseSession(ctx context.Context, req *rpc.CloseSessionReq) (*rpc.Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, req.GetSessionId())
+	return success(), nil
+}
+
+func (s *service) DeleteColumns(ctx context.Context, req *rpc.DeleteColumnsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	s.store.deleteSeries(req.GetPaths(), nil)
+	return success(), nil
+}
+
+func (s *service) InsertColumnRecords(ctx context.Context, req *rpc.InsertColumnRecordsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	return s.insertColumns(req.GetPaths(), req.GetTimestamps(), req.GetValuesList(), req.GetBitmapList(), req.GetDataTypeList(), req.GetTagsList()), nil
+}
+
+func (s *service) InsertNonAlignedColumnRecords(ctx context.Context, req *rpc.InsertNonAlignedColumnRecordsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	return s.insertColumns(req.GetPaths(), req.GetTimestamps(), req.GetValuesList(), req.GetBitmapList(), req.GetDataTypeList(), req.GetTagsList()), nil
+}
+
+func (s *service) InsertRowRecords(ctx context.Context, req *rpc.InsertRowRecordsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	return s.insertRows(req.GetPaths(), req.GetTimestamps(), req.GetValuesList(), req.GetBitmapList(), req.GetDataTypeList(), req.GetTagsList()), nil
+}
+
+func (s *service) InsertNonAlignedRowRecords(ctx context.Context, req *rpc.InsertNonAlignedRowRecordsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	return s.insertRows(req.GetPaths(), req.GetTimestamps(), req.GetValuesList(), req.GetBitmapList(), req.GetDataTypeList(), req.GetTagsList()), nil
+}
+
+// insertColumns 解码按列组织的数据，valuesList 中每一项为一个序列的非空值，位图标记了有值的时间戳
+func (s *service) insertColumns(paths []string, timeBuffer []byte, valuesList, bitmapList [][]byte, types []rpc.DataType, tagsList []map[string]string) *rpc.Status {
+	timestamps := client.GetLongArrayFromBytes(timeBuffer)
+	if len(valuesList) != len(paths) || len(bitmapList) != len(paths) || len(types) != len(paths) {
+		return executionError(errors.New("the sizes of paths, valuesList, bitmapList and dataTypeList should be equal"))
+	}
+	var points []point
+	for i, path := range paths {
+		bitmap := client.NewBitmapWithBuf(len(timestamps), bitmapList[i])
+		buffer := valuesList[i]
+		for j, timestamp := range timestamps {
+			if notNil, _ := bitmap.Get(j); !notNil {
+				continue
+			}
+			var value interface{}
+			value, buffer = client.GetValueFromBytes(buffer, types[i])
+			points = append(points, point{path: path, tags: tagsAt(tagsList, i), dataType: types[i], timestamp: timestamp, value: value})
+		}
+	}
+	if err := s.store.write(points); err != nil {
+		return executionError(err)
+	}
+	return success()
+}
+
+// insertRows 解码按行组织的数据，valuesList 中每一项为一个时间戳的非空值，位图标记了有值的序列
+func (s *service) insertRows(paths []string, timeBuffer []byte, valuesList, bitmapList [][]byte, types []rpc.DataType, tagsList []map[string]string) *rpc.Status {
+	timestamps := client.GetLongArrayFromBytes(timeBuffer)
+	if len(valuesList) != len(timestamps) || len(bitmapList) != len(timestamps) || len(types) != len(paths) {
+		return executionError(errors.New("the sizes of timestamps, valuesList and bitmapList should be equal"))
+	}
+	var points []point
+	for i, timestamp := range timestamps {
+		bitmap := client.NewBitmapWithBuf(len(paths), bitmapList[i])
+		buffer := valuesList[i]
+		for j, path := range paths {
+			if notNil, _ := bitmap.Get(j); !notNil {
+				continue
+			}
+			var value interface{}
+			value, buffer = client.GetValueFromBytes(buffer, types[j])
+			points = append(points, point{path: path, tags: tagsAt(tagsList, j), dataType: types[j], timestamp: timestamp, value: value})
+		}
+	}
+	if err := s.store.write(points); err != nil {
+		return executionError(err)
+	}
+	return success()
+}
+
+func tagsAt(tagsList []map[string]string, i int) map[string]string {
+	if i < len(tagsList) {
+		return tagsList[i]
+	}
+	return nil
+}
+
+func (s *service) DeleteDataInColumns(ctx context.Context, req *rpc.DeleteDataInColumnsReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	s.store.deleteData(req.GetPaths(), req.GetStartTime(), req.GetEndTime(), req.GetTagsList())
+	return success(), nil
+}
+
+func (s *service) QueryData(ctx context.Context, req *rpc.QueryDataReq) (*rpc.QueryDataResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.QueryDataResp{Status: status}, nil
+	}
+	t := s.store.query(req.GetPaths(), req.GetStartTime(), req.GetEndTime(), req.GetTagsList())
+	dataSet, err := t.encode()
+	if err != nil {
+		return &rpc.QueryDataResp{Status: executionError(err)}, nil
+	}
+	return &rpc.QueryDataResp{Status: success(), Paths: t.paths, TagsList: t.tags, DataTypeList: t.types, QueryDataSet: dataSet}, nil
+}
+
+func (s *service) AddStorageEngines(ctx context.Context, req *rpc.AddStorageEnginesReq) (*rpc.Status, error) {
+	return executionError(errNotSupported), nil
+}
+
+func (s *service) AggregateQuery(ctx context.Context, req *rpc.AggregateQueryReq) (*rpc.AggregateQueryResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.AggregateQueryResp{Status: status}, nil
+	}
+	result, err := s.store.aggregateQuery(req.GetPaths(), req.GetStartTime(), req.GetEndTime(), req.GetAggregateType(), req.GetTagsList())
+	if err != nil {
+		return &rpc.AggregateQueryResp{Status: executionError(err)}, nil
+	}
+	timestamps, values, err := result.encode()
+	if err != nil {
+		return &rpc.AggregateQueryResp{Status: executionError(err)}, nil
+	}
+	return &rpc.AggregateQueryResp{Status: success(), Paths: result.paths, TagsList: result.tags, DataTypeList: result.types, Timestamps: timestamps, ValuesList: values}, nil
+}
+
+func (s *service) LastQuery(ctx context.Context, req *rpc.LastQueryReq) (*rpc.LastQueryResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.LastQueryResp{Status: status}, nil
+	}
+	t := s.store.last(req.GetPaths(), req.GetStartTime(), math.MaxInt64, req.GetTagsList())
+	dataSet, err := t.encode()
+	if err != nil {
+		return &rpc.LastQueryResp{Status: executionError(err)}, nil
+	}
+	return &rpc.LastQueryResp{Status: success(), Paths: t.paths, TagsList: t.tags, DataTypeList: t.types, QueryDataSet: dataSet}, nil
+}
+
+func (s *service) DownsampleQuery(ctx context.Context, req *rpc.DownsampleQueryReq) (*rpc.DownsampleQueryResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.DownsampleQueryResp{Status: status}, nil
+	}
+	t, err := s.store.downsample(req.GetPaths(), req.GetStartTime(), req.GetEndTime(), req.GetAggregateType(), req.GetPrecision(), req.GetTagsList())
+	if err != nil {
+		return &rpc.DownsampleQueryResp{Status: executionError(err)}, nil
+	}
+	dataSet, err := t.encode()
+	if err != nil {
+		return &rpc.DownsampleQueryResp{Status: executionError(err)}, nil
+	}
+	return &rpc.DownsampleQueryResp{Status: success(), Paths: t.paths, TagsList: t.tags, DataTypeList: t.types, QueryDataSet: dataSet}, nil
+}
+
+func (s *service) ShowColumns(ctx context.Context, req *rpc.ShowColumnsReq) (*rpc.ShowColumnsResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.ShowColumnsResp{Status: status}, nil
+	}
+	paths, tags, types := s.store.columns()
+	return &rpc.ShowColumnsResp{Status: success(), Paths: paths, TagsList: tags, DataTypeList: types}, nil
+}
+
+func (s *service) GetReplicaNum(ctx context.Context, req *rpc.GetReplicaNumReq) (*rpc.GetReplicaNumResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.GetReplicaNumResp{Status: status}, nil
+	}
+	return &rpc.GetReplicaNumResp{Status: success(), ReplicaNum: thrift.Int32Ptr(1)}, nil
+}
+
+func (s *service) ExecuteSql(ctx context.Context, req *rpc.ExecuteSqlReq) (*rpc.ExecuteSqlResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.ExecuteSqlResp{Status: status}, nil
+	}
+	return s.executeSQL(req.GetStatement()), nil
+}
+
+func (s *service) UpdateUser(ctx context.Context, req *rpc.UpdateUserReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[req.GetUsername()]
+	if !ok {
+		return executionError(errors.New("user " + req.GetUsername() + " does not exist")), nil
+	}
+	if req.IsSetPassword() {
+		u.password = req.GetPassword()
+	}
+	if req.IsSetAuths() {
+		u.auths = append([]rpc.AuthType(nil), req.GetAuths()...)
+	}
+	return success(), nil
+}
+
+func (s *service) AddUser(ctx context.Context, req *rpc.AddUserReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[req.GetUsername()]; ok {
+		return executionError(errors.New("user " + req.GetUsername() + " already exists")), nil
+	}
+	s.users[req.GetUsername()] = &user{
+		password: req.GetPassword(),
+		userType: rpc.UserType_OrdinaryUser,
+		auths:    append([]rpc.AuthType(nil), req.GetAuths()...),
+	}
+	return success(), nil
+}
+
+func (s *service) DeleteUser(ctx context.Context, req *rpc.DeleteUserReq) (*rpc.Status, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return status, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[req.GetUsername()]
+	if !ok {
+		return executionError(errors.New("user " + req.GetUsername() + " does not exist")), nil
+	}
+	if u.userType == rpc.UserType_Administrator {
+		return executionError(errors.New("administrator can not be deleted")), nil
+	}
+	delete(s.users, req.GetUsername())
+	return success(), nil
+}
+
+func (s *service) GetUser(ctx context.Context, req *rpc.GetUserReq) (*rpc.GetUserResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.GetUserResp{Status: status}, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usernames := req.GetUsernames()
+	if len(usernames) == 0 {
+		for username := range s.users {
+			usernames = append(usernames, username)
+		}
+		sort.Strings(usernames)
+	}
+	resp := &rpc.GetUserResp{Status: success()}
+	for _, username := range usernames {
+		u, ok := s.users[username]
+		if !ok {
+			continue
+		}
+		resp.Usernames = append(resp.Usernames, username)
+		resp.UserTypes = append(resp.UserTypes, u.userType)
+		resp.Auths = append(resp.Auths, append([]rpc.AuthType(nil), u.auths...))
+	}
+	return resp, nil
+}
+
+func (s *service) GetClusterInfo(ctx context.Context, req *rpc.GetClusterInfoReq) (*rpc.GetClusterInfoResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.GetClusterInfoResp{Status: status}, nil
+	}
+	return &rpc.GetClusterInfoResp{Status: success()}, nil
+}
+
+func (s *service) ExecuteStatement(ctx context.Context, req *rpc.ExecuteStatementReq) (*rpc.ExecuteStatementResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.ExecuteStatementResp{Status: status}, nil
+	}
+	stmt, err := parseSQL(req.GetStatement())
+	if err != nil {
+		return &rpc.ExecuteStatementResp{Status: failure(client.StatementParseErrorCode, err)}, nil
+	}
+	query, ok := stmt.(*selectStatement)
+	if !ok {
+		resp := s.execute(stmt)
+		return &rpc.ExecuteStatementResp{Status: resp.Status, Type: resp.Type}, nil
+	}
+	t, err := s.selectTable(query)
+	if err != nil {
+		return &rpc.ExecuteStatementResp{Status: executionError(err)}, nil
+	}
+	rows, bitmaps, err := t.encodeStream()
+	if err != nil {
+		return &rpc.ExecuteStatementResp{Status: executionError(err)}, nil
+	}
+
+	s.mu.Lock()
+	s.nextQueryId++
+	queryId := s.nextQueryId
+	s.queries[queryId] = &streamQuery{rows: rows, bitmaps: bitmaps}
+	s.mu.Unlock()
+
+	columns := append([]string{"key"}, t.paths...)
+	types := append([]rpc.DataType{rpc.DataType_LONG}, t.types...)
+	var tags []map[string]string
+	if t.tags != nil {
+		tags = append([]map[string]string{nil}, t.tags...)
+	}
+	dataSet, _ := s.fetch(queryId, req.GetFetchSize())
+	return &rpc.ExecuteStatementResp{
+		Status:       success(),
+		Type:         rpc.SqlType_Query,
+		QueryId:      thrift.Int64Ptr(queryId),
+		Columns:      columns,
+		TagsList:     tags,
+		DataTypeList: types,
+		QueryDataSet: dataSet,
+	}, nil
+}
+
+// fetch 取出最多 fetchSize 行，fetchSize 不大于 0 时取出所有行，同时返回是否还有剩余的行。查询不存在时返回 nil
+func (s *service) fetch(queryId int64, fetchSize int32) (*rpc.QueryDataSetV2, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query, ok := s.queries[queryId]
+	if !ok {
+		return nil, false
+	}
+	n := len(query.rows)
+	if fetchSize > 0 && int(fetchSize) < n {
+		n = int(fetchSize)
+	}
+	dataSet := &rpc.QueryDataSetV2{ValuesList: query.rows[:n], BitmapList: query.bitmaps[:n]}
+	query.rows, query.bitmaps = query.rows[n:], query.bitmaps[n:]
+	return dataSet, len(query.rows) > 0
+}
+
+func (s *service) FetchResults(ctx context.Context, req *rpc.FetchResultsReq) (*rpc.FetchResultsResp, error) {
+	if status := s.checkSession(req.GetSessionId()); status != nil {
+		return &rpc.FetchResultsResp{Status: status}, nil
+	}
+	dataSet, hasMore := s.fetch(req.GetQueryId(), req.GetFetchSize())
+	if dataSet == nil {
+		return &rpc.FetchResultsResp{Status: executionError(errors.New("query does not exist"))}, nil
+	}
+	return &rpc.FetchResultsResp{Status: success(), HasMoreResults: hasMore, QueryDataSet: dataSet}, nil
+}
+
+func (s *service) CloseStatement(ctx context.Context, req *rpc.CloseStatementReq) (*rpc.Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.queries, req.GetQueryId())
+	return success(), nil
+}
+
+func (s *service) CommitTransformJob(ctx context.Context, req *rpc.CommitTransformJobReq) (*rpc.CommitTransformJobResp, error) {
+	return &rpc.CommitTransformJobResp{Status: executionError(errNotSupported)}, nil
+}
+
+func (s *service) QueryTransformJobStatus(ctx context.Context, req *rpc.QueryTransformJobStatusReq) (*rpc.QueryTransformJobStatusResp, error) {
+	return &rpc.QueryTransformJobStatusResp{Status: executionError(errNotSupported)}, nil
+}
+
+func (s *service) ShowEligibleJob(ctx context.Context, req *rpc.ShowEligibleJobReq) (*rpc.ShowEligibleJobResp, error) {
+	return &rpc.ShowEligibleJobResp{Status: executionError(errNotSupported)}, nil
+}
+
+func (s *service) CancelTransformJob(ctx context.Context, req *rpc.CancelTransformJobReq) (*rpc.Status, error) {
+	return executionError(errNotSupported), nil
+}
+
+func (s *service) RegisterTask(ctx context.Context, req *rpc.RegisterTaskReq) (*rpc.Status, error) {
+	return executionError(errNotSupported), nil
+}
+
+func (s *service) DropTask(ctx context.Context, req *rpc.DropTaskReq) (*rpc.Status, error) {
+	return executionError(errNotSupported), nil
+}
+
+func (s *service) GetRegisterTaskInfo(ctx context.Context, req *rpc.GetRegisterTaskInfoReq) (*rpc.GetRegisterTaskInfoResp, error) {
+	return &rpc.GetRegisterTaskInfoResp{Status: executionError(errNotSupported)}, nil
+}
+
+func (s *service) CurveMatch(ctx context.Context, req *rpc.CurveMatchReq) (*rpc.CurveMatchResp, error) {
+	return &rpc.CurveMatchResp{Status: executionError(errNotSupported)}, nil
+}
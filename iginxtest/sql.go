@@ -0,0 +1,975 @@
+package iginxtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// 支持的 SQL 子集：
+//
+//	SELECT a, MAX(b) FROM p [WHERE TIME >= 0 AND TIME < 10 AND a > 1] [WITH k=v] [GROUP [0, 10) BY 5ms] [ORDER BY a [DESC]] [LIMIT n [OFFSET m]]
+//	INSERT INTO p[{k=v}] (TIMESTAMP, a, b) VALUES (1, 1, "one"), ...
+//	DELETE FROM p.* [WHERE TIME >= 0 AND TIME < 10] [WITH k=v]
+//	DELETE TIME SERIES p.a, p.b
+//	SHOW TIME SERIES, SHOW REPLICA NUMBER, SHOW CLUSTER INFO, COUNT POINTS, CLEAR DATA
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	stringToken
+	symbolToken
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func isWordChar(ch byte) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("_-:@#$%&+~.*", ch) >= 0
+}
+
+func tokenize(sql string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '\'' || ch == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(sql) && sql[j] != ch; j++ {
+				if sql[j] == '\\' && j+1 < len(sql) {
+					j++
+				}
+				sb.WriteByte(sql[j])
+			}
+			if j >= len(sql) {
+				return nil, errors.New("unterminated string literal")
+			}
+			tokens = append(tokens, token{kind: stringToken, value: sb.String()})
+			i = j + 1
+		case isWordChar(ch):
+			j := i
+			for j < len(sql) && isWordChar(sql[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: wordToken, value: sql[i:j]})
+			i = j
+		default:
+			symbol := string(ch)
+			if i+1 < len(sql) {
+				switch sql[i : i+2] {
+				case ">=", "<=", "!=", "<>", "==":
+					symbol = sql[i : i+2]
+				}
+			}
+			if !strings.Contains(symbol, "=") && !strings.Contains("()[]{},;<>", symbol) {
+				return nil, fmt.Errorf("unexpected character %q", ch)
+			}
+			tokens = append(tokens, token{kind: symbolToken, value: symbol})
+			i += len(symbol)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: symbolToken}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == wordToken && strings.EqualFold(t.value, keyword)
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectKeyword(keywords ...string) error {
+	for _, keyword := range keywords {
+		if !p.acceptKeyword(keyword) {
+			return fmt.Errorf("expect %s but got %q", keyword, p.peek().value)
+		}
+	}
+	return nil
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	t := p.peek()
+	if t.kind == symbolToken && t.value == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return fmt.Errorf("expect %s but got %q", symbol, p.peek().value)
+	}
+	return nil
+}
+
+func (p *parser) word() (string, error) {
+	t := p.next()
+	if t.kind != wordToken {
+		return "", fmt.Errorf("expect a name but got %q", t.value)
+	}
+	return t.value, nil
+}
+
+func (p *parser) integer() (int64, error) {
+	t := p.next()
+	if t.kind != wordToken {
+		return 0, fmt.Errorf("expect an integer but got %q", t.value)
+	}
+	return strconv.ParseInt(t.value, 10, 64)
+}
+
+// literal 解析常量，整数为 int64，小数为 float64
+func (p *parser) literal() (interface{}, error) {
+	t := p.next()
+	switch {
+	case t.kind == stringToken:
+		return t.value, nil
+	case t.kind != wordToken:
+		return nil, fmt.Errorf("expect a value but got %q", t.value)
+	case strings.EqualFold(t.value, "true"):
+		return true, nil
+	case strings.EqualFold(t.value, "false"):
+		return false, nil
+	}
+	if v, err := strconv.ParseInt(t.value, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(t.value, 64); err == nil {
+		return v, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", t.value)
+}
+
+func (p *parser) paths() ([]string, error) {
+	var paths []string
+	for {
+		path, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !p.acceptSymbol(",") {
+			return paths, nil
+		}
+	}
+}
+
+type selectItem struct {
+	path          string
+	aggregateType *rpc.AggregateType
+}
+
+type valueCondition struct {
+	path     string
+	operator string
+	value    interface{}
+}
+
+type selectStatement struct {
+	items      []selectItem
+	from       string
+	startTime  int64
+	endTime    int64
+	conditions []valueCondition
+	tags       map[string][]string
+
+	hasGroup  bool
+	precision int64
+
+	limit     int64
+	offset    int64
+	orderBy   string
+	ascending bool
+}
+
+type insertStatement struct {
+	prefix     string
+	tags       map[string]string
+	columns    []string
+	timestamps []int64
+	rows       [][]interface{}
+}
+
+type deleteStatement struct {
+	paths     []string
+	startTime int64
+	endTime   int64
+	tags      map[string][]string
+}
+
+type deleteSeriesStatement struct {
+	paths []string
+}
+
+type simpleStatement struct {
+	sqlType rpc.SqlType
+}
+
+func parseSQL(sql string) (interface{}, error) {
+	tokens, err := tokenize(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var stmt interface{}
+	switch {
+	case p.acceptKeyword("SELECT"):
+		stmt, err = p.parseSelect()
+	case p.acceptKeyword("INSERT"):
+		stmt, err = p.parseInsert()
+	case p.acceptKeyword("DELETE"):
+		if p.acceptKeyword("TIME") {
+			if err = p.expectKeyword("SERIES"); err == nil {
+				var paths []string
+				paths, err = p.paths()
+				stmt = &deleteSeriesStatement{paths: paths}
+			}
+		} else {
+			stmt, err = p.parseDelete()
+		}
+	case p.acceptKeyword("SHOW"):
+		switch {
+		case p.acceptKeyword("TIME"):
+			err = p.expectKeyword("SERIES")
+			stmt = &simpleStatement{sqlType: rpc.SqlType_ShowTimeSeries}
+		case p.acceptKeyword("REPLICA"):
+			err = p.expectKeyword("NUMBER")
+			stmt = &simpleStatement{sqlType: rpc.SqlType_GetReplicaNum}
+		case p.acceptKeyword("CLUSTER"):
+			err = p.expectKeyword("INFO")
+			stmt = &simpleStatement{sqlType: rpc.SqlType_ShowClusterInfo}
+		default:
+			err = fmt.Errorf("unsupported statement SHOW %s", p.peek().value)
+		}
+	case p.acceptKeyword("COUNT"):
+		err = p.expectKeyword("POINTS")
+		stmt = &simpleStatement{sqlType: rpc.SqlType_CountPoints}
+	case p.acceptKeyword("CLEAR"):
+		err = p.expectKeyword("DATA")
+		stmt = &simpleStatement{sqlType: rpc.SqlType_ClearData}
+	default:
+		err = fmt.Errorf("unsupported statement %q", p.peek().value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.acceptSymbol(";")
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.peek().value)
+	}
+	return stmt, nil
+}
+
+func (p *parser) parseSelect() (*selectStatement, error) {
+	stmt := &selectStatement{startTime: math.MinInt64, endTime: math.MaxInt64, ascending: true}
+	for {
+		name, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		item := selectItem{path: name}
+		if p.acceptSymbol("(") {
+			aggregateType, err := rpc.AggregateTypeFromString(strings.ToUpper(name))
+			if err != nil {
+				return nil, fmt.Errorf("unsupported function %s", name)
+			}
+			if item.path, err = p.word(); err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			item.aggregateType = &aggregateType
+		}
+		stmt.items = append(stmt.items, item)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	var err error
+	if stmt.from, err = p.word(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		if stmt.conditions, err = p.parseWhere(&stmt.startTime, &stmt.endTime); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("WITH") {
+		if stmt.tags, err = p.parseWith(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectSymbol("["); err != nil {
+			return nil, err
+		}
+		if stmt.startTime, err = p.integer(); err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+		if stmt.endTime, err = p.integer(); err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		duration, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		if stmt.precision, err = parseDuration(duration); err != nil {
+			return nil, err
+		}
+		stmt.hasGroup = true
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if stmt.orderBy, err = p.word(); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("DESC") {
+			stmt.ascending = false
+		} else {
+			p.acceptKeyword("ASC")
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if stmt.limit, err = p.integer(); err != nil {
+			return nil, err
+		}
+		if p.acceptSymbol(",") {
+			// LIMIT offset, count
+			stmt.offset = stmt.limit
+			if stmt.limit, err = p.integer(); err != nil {
+				return nil, err
+			}
+		} else if p.acceptKeyword("OFFSET") {
+			if stmt.offset, err = p.integer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return stmt, nil
+}
+
+// parseWhere 解析由 AND 连接的条件，时间条件收窄 [startTime, endTime)，其他条件为值过滤
+func (p *parser) parseWhere(startTime, endTime *int64) ([]valueCondition, error) {
+	var conditions []valueCondition
+	for {
+		path, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		operator := p.next()
+		if operator.kind != symbolToken {
+			return nil, fmt.Errorf("expect an operator but got %q", operator.value)
+		}
+		if strings.EqualFold(path, "TIME") || strings.EqualFold(path, "KEY") {
+			timestamp, err := p.integer()
+			if err != nil {
+				return nil, err
+			}
+			switch operator.value {
+			case ">=":
+				*startTime = maxInt64(*startTime, timestamp)
+			case ">":
+				*startTime = maxInt64(*startTime, timestamp+1)
+			case "<":
+				*endTime = minInt64(*endTime, timestamp)
+			case "<=":
+				*endTime = minInt64(*endTime, timestamp+1)
+			case "=", "==":
+				*startTime = maxInt64(*startTime, timestamp)
+				*endTime = minInt64(*endTime, timestamp+1)
+			default:
+				return nil, fmt.Errorf("unsupported time operator %s", operator.value)
+			}
+		} else {
+			value, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, valueCondition{path: path, operator: operator.value, value: value})
+		}
+		if !p.acceptKeyword("AND") {
+			return conditions, nil
+		}
+	}
+}
+
+// parseWith 解析标签条件，同一个键的多个值之间为 OR 关系
+func (p *parser) parseWith() (map[string][]string, error) {
+	tags := make(map[string][]string)
+	for {
+		key, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return nil, err
+		}
+		value, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = append(tags[key], value)
+		if !p.acceptKeyword("AND") && !p.acceptKeyword("OR") {
+			return tags, nil
+		}
+	}
+}
+
+func (p *parser) parseInsert() (*insertStatement, error) {
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+	stmt := &insertStatement{}
+	var err error
+	if stmt.prefix, err = p.word(); err != nil {
+		return nil, err
+	}
+	if p.acceptSymbol("{") {
+		stmt.tags = make(map[string]string)
+		for {
+			key, err := p.word()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol("="); err != nil {
+				return nil, err
+			}
+			if stmt.tags[key], err = p.word(); err != nil {
+				return nil, err
+			}
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol("}"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	if !p.acceptKeyword("TIMESTAMP") && !p.acceptKeyword("TIME") && !p.acceptKeyword("KEY") {
+		return nil, errors.New("the first column of insert should be TIMESTAMP")
+	}
+	for p.acceptSymbol(",") {
+		column, err := p.word()
+		if err != nil {
+			return nil, err
+		}
+		stmt.columns = append(stmt.columns, column)
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		timestamp, err := p.integer()
+		if err != nil {
+			return nil, err
+		}
+		var row []interface{}
+		for p.acceptSymbol(",") {
+			if p.acceptKeyword("NULL") {
+				row = append(row, nil)
+				continue
+			}
+			value, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, value)
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		if len(row) != len(stmt.columns) {
+			return nil, fmt.Errorf("row at %d has %d values, but there are %d columns", timestamp, len(row), len(stmt.columns))
+		}
+		stmt.timestamps = append(stmt.timestamps, timestamp)
+		stmt.rows = append(stmt.rows, row)
+		if !p.acceptSymbol(",") {
+			return stmt, nil
+		}
+	}
+}
+
+func (p *parser) parseDelete() (*deleteStatement, error) {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	stmt := &deleteStatement{startTime: math.MinInt64, endTime: math.MaxInt64}
+	var err error
+	if stmt.paths, err = p.paths(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		conditions, err := p.parseWhere(&stmt.startTime, &stmt.endTime)
+		if err != nil {
+			return nil, err
+		}
+		if len(conditions) > 0 {
+			return nil, errors.New("delete only supports time conditions")
+		}
+	}
+	if p.acceptKeyword("WITH") {
+		if stmt.tags, err = p.parseWith(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// parseDuration 解析 5ms、1s 等时长，返回毫秒数，没有单位时为毫秒
+func parseDuration(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		millis int64
+	}{{"ms", 1}, {"s", 1000}, {"min", 60 * 1000}, {"m", 60 * 1000}, {"h", 60 * 60 * 1000}, {"d", 24 * 60 * 60 * 1000}}
+	lower := strings.ToLower(s)
+	for _, unit := range units {
+		if strings.HasSuffix(lower, unit.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(lower, unit.suffix), 10, 64)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid duration %s", s)
+			}
+			return n * unit.millis, nil
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	return n, nil
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (s *service) executeSQL(sql string) *rpc.ExecuteSqlResp {
+	stmt, err := parseSQL(sql)
+	if err != nil {
+		return &rpc.ExecuteSqlResp{
+			Status:        failure(client.StatementParseErrorCode, err),
+			Type:          rpc.SqlType_Unknown,
+			ParseErrorMsg: thrift.StringPtr(err.Error()),
+		}
+	}
+	return s.execute(stmt)
+}
+
+func (s *service) execute(stmt interface{}) *rpc.ExecuteSqlResp {
+	switch stmt := stmt.(type) {
+	case *selectStatement:
+		return s.executeSelect(stmt)
+	case *insertStatement:
+		if err := s.executeInsert(stmt); err != nil {
+			return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Insert}
+		}
+		return &rpc.ExecuteSqlResp{Status: success(), Type: rpc.SqlType_Insert}
+	case *deleteStatement:
+		s.store.deleteData(stmt.paths, stmt.startTime, stmt.endTime, stmt.tags)
+		return &rpc.ExecuteSqlResp{Status: success(), Type: rpc.SqlType_Delete}
+	case *deleteSeriesStatement:
+		s.store.deleteSeries(stmt.paths, nil)
+		return &rpc.ExecuteSqlResp{Status: success(), Type: rpc.SqlType_DeleteTimeSeries}
+	case *simpleStatement:
+		resp := &rpc.ExecuteSqlResp{Status: success(), Type: stmt.sqlType}
+		switch stmt.sqlType {
+		case rpc.SqlType_ShowTimeSeries:
+			resp.Paths, resp.TagsList, resp.DataTypeList = s.store.columns()
+		case rpc.SqlType_GetReplicaNum:
+			resp.ReplicaNum = thrift.Int32Ptr(1)
+		case rpc.SqlType_CountPoints:
+			resp.PointsNum = thrift.Int64Ptr(s.store.countPoints())
+		case rpc.SqlType_ClearData:
+			s.store.clear()
+		}
+		return resp
+	}
+	return &rpc.ExecuteSqlResp{Status: executionError(errNotSupported), Type: rpc.SqlType_Unknown}
+}
+
+// selectAggregate 返回语句中的聚合函数，所有列需要使用相同的聚合函数或都不使用
+func (stmt *selectStatement) selectAggregate() (*rpc.AggregateType, error) {
+	aggregateType := stmt.items[0].aggregateType
+	for _, item := range stmt.items[1:] {
+		if (item.aggregateType == nil) != (aggregateType == nil) ||
+			(aggregateType != nil && *item.aggregateType != *aggregateType) {
+			return nil, errors.New("different aggregate functions in one statement are not supported by iginxtest")
+		}
+	}
+	if stmt.hasGroup && aggregateType == nil {
+		return nil, errors.New("GROUP BY needs an aggregate function")
+	}
+	return aggregateType, nil
+}
+
+func (stmt *selectStatement) fullPaths() []string {
+	paths := make([]string, len(stmt.items))
+	for i, item := range stmt.items {
+		paths[i] = stmt.from + "." + item.path
+	}
+	return paths
+}
+
+func (s *service) executeSelect(stmt *selectStatement) *rpc.ExecuteSqlResp {
+	aggregateType, err := stmt.selectAggregate()
+	if err != nil {
+		return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Query}
+	}
+	// 不分组的聚合查询（LAST 除外）每个序列只返回一个值
+	if aggregateType != nil && !stmt.hasGroup && *aggregateType != rpc.AggregateType_LAST {
+		result, err := s.store.aggregateQuery(stmt.fullPaths(), stmt.startTime, stmt.endTime, *aggregateType, stmt.tags)
+		if err != nil {
+			return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Query}
+		}
+		timestamps, values, err := result.encode()
+		if err != nil {
+			return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Query}
+		}
+		return &rpc.ExecuteSqlResp{
+			Status:        success(),
+			Type:          rpc.SqlType_Query,
+			Paths:         result.paths,
+			TagsList:      result.tags,
+			DataTypeList:  result.types,
+			Timestamps:    timestamps,
+			ValuesList:    values,
+			AggregateType: aggregateType,
+		}
+	}
+
+	t, err := s.selectTable(stmt)
+	if err != nil {
+		return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Query}
+	}
+	dataSet, err := t.encode()
+	if err != nil {
+		return &rpc.ExecuteSqlResp{Status: executionError(err), Type: rpc.SqlType_Query}
+	}
+	resp := &rpc.ExecuteSqlResp{
+		Status:       success(),
+		Type:         rpc.SqlType_Query,
+		Paths:        t.paths,
+		TagsList:     t.tags,
+		DataTypeList: t.types,
+		QueryDataSet: dataSet,
+	}
+	if stmt.limit > 0 {
+		resp.Limit = thrift.Int32Ptr(int32(stmt.limit))
+		resp.Offset = thrift.Int32Ptr(int32(stmt.offset))
+	}
+	if stmt.orderBy != "" {
+		resp.OrderByPath = thrift.StringPtr(stmt.orderBy)
+		resp.Ascending = thrift.BoolPtr(stmt.ascending)
+	}
+	return resp
+}
+
+// selectTable 执行查询并返回按时间对齐的结果，不分组的聚合查询返回时间戳为 0 的一行
+func (s *service) selectTable(stmt *selectStatement) (*table, error) {
+	aggregateType, err := stmt.selectAggregate()
+	if err != nil {
+		return nil, err
+	}
+
+	var t *table
+	switch {
+	case aggregateType == nil:
+		t = s.store.query(stmt.fullPaths(), stmt.startTime, stmt.endTime, stmt.tags)
+	case stmt.hasGroup:
+		t, err = s.store.downsample(stmt.fullPaths(), stmt.startTime, stmt.endTime, *aggregateType, stmt.precision, stmt.tags)
+	case *aggregateType == rpc.AggregateType_LAST:
+		t = s.store.last(stmt.fullPaths(), stmt.startTime, stmt.endTime, stmt.tags)
+	default:
+		var result *aggregateResult
+		result, err = s.store.aggregateQuery(stmt.fullPaths(), stmt.startTime, stmt.endTime, *aggregateType, stmt.tags)
+		if err == nil {
+			t = &table{paths: result.paths, tags: result.tags, types: result.types, timestamps: []int64{0}, rows: [][]interface{}{result.values}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.filter(stmt.from, stmt.conditions); err != nil {
+		return nil, err
+	}
+	if stmt.orderBy != "" {
+		if err := t.orderBy(stmt.from+"."+stmt.orderBy, stmt.ascending); err != nil {
+			return nil, err
+		}
+	}
+	t.limit(stmt.limit, stmt.offset)
+	return t, nil
+}
+
+// filter 保留满足所有值过滤条件的行，路径相同但标签不同的多个序列中任意一个满足即可
+func (t *table) filter(prefix string, conditions []valueCondition) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	var timestamps []int64
+	var rows [][]interface{}
+	for i, row := range t.rows {
+		keep := true
+		for _, condition := range conditions {
+			matched, err := t.match(row, prefix+"."+condition.path, condition)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			timestamps = append(timestamps, t.timestamps[i])
+			rows = append(rows, row)
+		}
+	}
+	t.timestamps, t.rows = timestamps, rows
+	return nil
+}
+
+func (t *table) match(row []interface{}, path string, condition valueCondition) (bool, error) {
+	for j := range t.paths {
+		if t.paths[j] != path || row[j] == nil {
+			continue
+		}
+		c, err := compare(row[j], condition.value)
+		if err != nil {
+			return false, err
+		}
+		var ok bool
+		switch condition.operator {
+		case "=", "==":
+			ok = c == 0
+		case "!=", "<>":
+			ok = c != 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		default:
+			return false, fmt.Errorf("unsupported operator %s", condition.operator)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compare 比较两个值，数值之间按浮点数比较，字符串按字典序比较
+func compare(a, b interface{}) (int, error) {
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("can not compare %v with %v", a, b)
+		}
+		return strings.Compare(sa, sb), nil
+	}
+	fa, fb := toFloat64(a), toFloat64(b)
+	if math.IsNaN(fa) || math.IsNaN(fb) {
+		return 0, fmt.Errorf("can not compare %v with %v", a, b)
+	}
+	switch {
+	case fa < fb:
+		return -1, nil
+	case fa > fb:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// orderBy 按 path 对应列的值排序，空值排在最后，值相同时按时间排序
+func (t *table) orderBy(path string, ascending bool) error {
+	column := -1
+	for j := range t.paths {
+		if t.paths[j] == path {
+			column = j
+			break
+		}
+	}
+	if column < 0 {
+		return nil
+	}
+	index := make([]int, len(t.rows))
+	for i := range index {
+		index[i] = i
+	}
+	var sortErr error
+	sort.SliceStable(index, func(x, y int) bool {
+		a, b := t.rows[index[x]][column], t.rows[index[y]][column]
+		if a == nil || b == nil {
+			return a != nil
+		}
+		c, err := compare(a, b)
+		if err != nil {
+			sortErr = err
+		}
+		if ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	timestamps := make([]int64, len(index))
+	rows := make([][]interface{}, len(index))
+	for i, k := range index {
+		timestamps[i], rows[i] = t.timestamps[k], t.rows[k]
+	}
+	t.timestamps, t.rows = timestamps, rows
+	return nil
+}
+
+func (t *table) limit(limit, offset int64) {
+	if offset > int64(len(t.rows)) {
+		offset = int64(len(t.rows))
+	}
+	t.timestamps, t.rows = t.timestamps[offset:], t.rows[offset:]
+	if limit > 0 && limit < int64(len(t.rows)) {
+		t.timestamps, t.rows = t.timestamps[:limit], t.rows[:limit]
+	}
+}
+
+// executeInsert 写入数据，整数写入已有的 INTEGER 或 FLOAT、DOUBLE 序列时会转换类型
+func (s *service) executeInsert(stmt *insertStatement) error {
+	s.store.mu.RLock()
+	existingTypes := make(map[string]rpc.DataType)
+	for _, column := range stmt.columns {
+		path := stmt.prefix + "." + column
+		if ts, ok := s.store.series[seriesKey(path, stmt.tags)]; ok {
+			existingTypes[path] = ts.dataType
+		}
+	}
+	s.store.mu.RUnlock()
+
+	var points []point
+	for i, row := range stmt.rows {
+		for j, value := range row {
+			if value == nil {
+				continue
+			}
+			path := stmt.prefix + "." + stmt.columns[j]
+			dataType, value := literalType(value)
+			if existing, ok := existingTypes[path]; ok {
+				dataType, value = convertLiteral(value, dataType, existing)
+			}
+			points = append(points, point{path: path, tags: stmt.tags, dataType: dataType, timestamp: stmt.timestamps[i], value: value})
+		}
+	}
+	return s.store.write(points)
+}
+
+func literalType(value interface{}) (rpc.DataType, interface{}) {
+	switch value.(type) {
+	case bool:
+		return rpc.DataType_BOOLEAN, value
+	case int64:
+		return rpc.DataType_LONG, value
+	case float64:
+		return rpc.DataType_DOUBLE, value
+	}
+	return rpc.DataType_BINARY, value
+}
+
+func convertLiteral(value interface{}, from, to rpc.DataType) (rpc.DataType, interface{}) {
+	switch {
+	case from == rpc.DataType_LONG && to == rpc.DataType_INTEGER:
+		v := value.(int64)
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return to, int32(v)
+		}
+	case from == rpc.DataType_LONG && to == rpc.DataType_DOUBLE:
+		return to, float64(value.(int64))
+	case from == rpc.DataType_LONG && to == rpc.DataType_FLOAT:
+		return to, float32(value.(int64))
+	case from == rpc.DataType_DOUBLE && to == rpc.DataType_FLOAT:
+		return to, float32(value.(float64))
+	}
+	return from, value
+}
@@ -0,0 +1,307 @@
+package iginxtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// series 是内存中的一条时间序列，由路径和标签唯一确定
+type series struct {
+	path     string
+	tags     map[string]string
+	dataType rpc.DataType
+	points   map[int64]interface{}
+}
+
+func (s *series) key() string {
+	return seriesKey(s.path, s.tags)
+}
+
+func (s *series) timestamps(startTime, endTime int64) []int64 {
+	var ret []int64
+	for timestamp := range s.points {
+		if timestamp >= startTime && timestamp < endTime {
+			ret = append(ret, timestamp)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+func seriesKey(path string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return path
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+tags[k])
+	}
+	return path + "{" + strings.Join(parts, ",") + "}"
+}
+
+// store 是线程安全的内存时序数据库
+type store struct {
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+func newStore() *store {
+	return &store{series: make(map[string]*series)}
+}
+
+// point 是一个待写入的数据点
+type point struct {
+	path      string
+	tags      map[string]string
+	dataType  rpc.DataType
+	timestamp int64
+	value     interface{}
+}
+
+// write 写入一组数据点，任意一点的类型与已有序列或同一批中的其他点不一致时不写入任何数据
+func (s *store) write(points []point) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	types := make(map[string]rpc.DataType)
+	for _, p := range points {
+		key := seriesKey(p.path, p.tags)
+		dataType, ok := types[key]
+		if !ok {
+			if existing, exists := s.series[key]; exists {
+				dataType, ok = existing.dataType, true
+			}
+		}
+		if ok && dataType != p.dataType {
+			return fmt.Errorf("data type of %s is %s, but %s is given", key, dataType, p.dataType)
+		}
+		types[key] = p.dataType
+	}
+	for _, p := range points {
+		key := seriesKey(p.path, p.tags)
+		ts, ok := s.series[key]
+		if !ok {
+			ts = &series{
+				path:     p.path,
+				tags:     copyTags(p.tags),
+				dataType: p.dataType,
+				points:   make(map[int64]interface{}),
+			}
+			s.series[key] = ts
+		}
+		ts.points[p.timestamp] = p.value
+	}
+	return nil
+}
+
+// match 返回路径和标签都匹配的序列，按路径和标签排序
+func (s *store) match(patterns []string, tagFilter map[string][]string) []*series {
+	var ret []*series
+	for _, ts := range s.series {
+		if matchAnyPath(patterns, ts.path) && matchTagFilter(tagFilter, ts.tags) {
+			ret = append(ret, ts)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].key() < ret[j].key()
+	})
+	return ret
+}
+
+// deleteData 删除匹配的序列在 [startTime, endTime) 内的数据，序列本身保留
+func (s *store) deleteData(patterns []string, startTime, endTime int64, tagFilter map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ts := range s.match(patterns, tagFilter) {
+		for timestamp := range ts.points {
+			if timestamp >= startTime && timestamp < endTime {
+				delete(ts.points, timestamp)
+			}
+		}
+	}
+}
+
+func (s *store) deleteSeries(patterns []string, tagFilter map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ts := range s.match(patterns, tagFilter) {
+		delete(s.series, ts.key())
+	}
+}
+
+func (s *store) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series = make(map[string]*series)
+}
+
+func (s *store) countPoints() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var count int64
+	for _, ts := range s.series {
+		count += int64(len(ts.points))
+	}
+	return count
+}
+
+// matchAnyPath 判断 path 是否与任意一个模式匹配，模式中的 * 匹配一级或多级节点
+func matchAnyPath(patterns []string, path string) bool {
+	nodes := strings.Split(path, ".")
+	for _, pattern := range patterns {
+		if matchNodes(strings.Split(pattern, "."), nodes) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNodes(pattern, nodes []string) bool {
+	if len(pattern) == 0 {
+		return len(nodes) == 0
+	}
+	if pattern[0] == "*" {
+		for i := 1; i <= len(nodes); i++ {
+			if matchNodes(pattern[1:], nodes[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(nodes) > 0 && pattern[0] == nodes[0] && matchNodes(pattern[1:], nodes[1:])
+}
+
+// matchTagFilter 判断标签是否满足过滤条件，每个键的值需要在给定的列表中，列表中的 * 匹配任意值
+func matchTagFilter(filter map[string][]string, tags map[string]string) bool {
+	for k, values := range filter {
+		value, ok := tags[k]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, v := range values {
+			if v == "*" || v == value {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	ret := make(map[string]string, len(tags))
+	for k, v := range tags {
+		ret[k] = v
+	}
+	return ret
+}
+
+// aggregate 计算一组按时间排序的数据点的聚合值，返回聚合结果的类型、值以及 FIRST、LAST 对应的时间戳
+func aggregate(aggregateType rpc.AggregateType, dataType rpc.DataType, timestamps []int64, values []interface{}) (rpc.DataType, interface{}, int64, error) {
+	if aggregateType == rpc.AggregateType_COUNT {
+		return rpc.DataType_LONG, int64(len(values)), 0, nil
+	}
+	if len(values) == 0 {
+		return dataType, nil, 0, nil
+	}
+	switch aggregateType {
+	case rpc.AggregateType_FIRST_VALUE, rpc.AggregateType_FIRST:
+		return dataType, values[0], timestamps[0], nil
+	case rpc.AggregateType_LAST_VALUE, rpc.AggregateType_LAST:
+		return dataType, values[len(values)-1], timestamps[len(values)-1], nil
+	}
+
+	if dataType == rpc.DataType_BOOLEAN || dataType == rpc.DataType_BINARY {
+		return dataType, nil, 0, errors.New(aggregateType.String() + " is not supported for " + dataType.String())
+	}
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		numbers[i] = toFloat64(value)
+	}
+	switch aggregateType {
+	case rpc.AggregateType_MAX, rpc.AggregateType_MIN:
+		best := 0
+		for i := range numbers {
+			if (aggregateType == rpc.AggregateType_MAX && numbers[i] > numbers[best]) ||
+				(aggregateType == rpc.AggregateType_MIN && numbers[i] < numbers[best]) {
+				best = i
+			}
+		}
+		return dataType, values[best], 0, nil
+	case rpc.AggregateType_SUM:
+		if dataType == rpc.DataType_INTEGER || dataType == rpc.DataType_LONG {
+			var sum int64
+			for _, value := range values {
+				sum += int64(toFloat64(value))
+			}
+			return rpc.DataType_LONG, sum, 0, nil
+		}
+		var sum float64
+		for _, number := range numbers {
+			sum += number
+		}
+		return rpc.DataType_DOUBLE, sum, 0, nil
+	case rpc.AggregateType_AVG:
+		var sum float64
+		for _, number := range numbers {
+			sum += number
+		}
+		return rpc.DataType_DOUBLE, sum / float64(len(numbers)), 0, nil
+	}
+	return dataType, nil, 0, errors.New("unknown aggregate type " + aggregateType.String())
+}
+
+// aggregateResultType 返回聚合结果的类型，没有数据时也需要确定列的类型
+func aggregateResultType(aggregateType rpc.AggregateType, dataType rpc.DataType) rpc.DataType {
+	switch aggregateType {
+	case rpc.AggregateType_COUNT:
+		return rpc.DataType_LONG
+	case rpc.AggregateType_AVG:
+		return rpc.DataType_DOUBLE
+	case rpc.AggregateType_SUM:
+		if dataType == rpc.DataType_INTEGER || dataType == rpc.DataType_LONG {
+			return rpc.DataType_LONG
+		}
+		return rpc.DataType_DOUBLE
+	}
+	return dataType
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return math.NaN()
+}
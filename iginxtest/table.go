@@ -0,0 +1,265 @@
+package iginxtest
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+// table 是按时间戳对齐的查询结果，每一列为一个序列
+type table struct {
+	paths      []string
+	tags       []map[string]string
+	types      []rpc.DataType
+	timestamps []int64
+	rows       [][]interface{}
+}
+
+func newTable(columns []*series) *table {
+	t := &table{}
+	hasTags := false
+	for _, ts := range columns {
+		t.paths = append(t.paths, ts.path)
+		t.tags = append(t.tags, ts.tags)
+		t.types = append(t.types, ts.dataType)
+		if len(ts.tags) > 0 {
+			hasTags = true
+		}
+	}
+	if !hasTags {
+		t.tags = nil
+	}
+	return t
+}
+
+// query 返回 [startTime, endTime) 内的原始数据，没有数据的序列不会出现在结果中
+func (s *store) query(patterns []string, startTime, endTime int64, tagFilter map[string][]string) *table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var columns []*series
+	rowIndex := make(map[int64]int)
+	var timestamps []int64
+	for _, ts := range s.match(patterns, tagFilter) {
+		seriesTimestamps := ts.timestamps(startTime, endTime)
+		if len(seriesTimestamps) == 0 {
+			continue
+		}
+		columns = append(columns, ts)
+		for _, timestamp := range seriesTimestamps {
+			if _, ok := rowIndex[timestamp]; !ok {
+				rowIndex[timestamp] = 0
+				timestamps = append(timestamps, timestamp)
+			}
+		}
+	}
+	sortInt64s(timestamps)
+
+	t := newTable(columns)
+	t.timestamps = timestamps
+	t.rows = make([][]interface{}, len(timestamps))
+	for i, timestamp := range timestamps {
+		rowIndex[timestamp] = i
+		t.rows[i] = make([]interface{}, len(columns))
+	}
+	for j, ts := range columns {
+		for timestamp, value := range ts.points {
+			if i, ok := rowIndex[timestamp]; ok && timestamp >= startTime && timestamp < endTime {
+				t.rows[i][j] = value
+			}
+		}
+	}
+	return t
+}
+
+// downsample 将 [startTime, endTime) 按 precision 分组聚合，每组的时间戳为组的起始时间
+func (s *store) downsample(patterns []string, startTime, endTime int64, aggregateType rpc.AggregateType, precision int64, tagFilter map[string][]string) (*table, error) {
+	if precision <= 0 {
+		return nil, errors.New("precision should be positive")
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var columns []*series
+	for _, ts := range s.match(patterns, tagFilter) {
+		if len(ts.timestamps(startTime, endTime)) > 0 {
+			columns = append(columns, ts)
+		}
+	}
+	t := newTable(columns)
+	for j, ts := range columns {
+		t.types[j] = aggregateResultType(aggregateType, ts.dataType)
+	}
+
+	for windowStart := startTime; windowStart < endTime; {
+		windowEnd := windowStart + precision
+		if windowEnd > endTime || windowEnd < windowStart {
+			windowEnd = endTime
+		}
+		row := make([]interface{}, len(columns))
+		hasValue := false
+		for j, ts := range columns {
+			timestamps := ts.timestamps(windowStart, windowEnd)
+			if len(timestamps) == 0 {
+				continue
+			}
+			values := make([]interface{}, len(timestamps))
+			for i, timestamp := range timestamps {
+				values[i] = ts.points[timestamp]
+			}
+			_, value, _, err := aggregate(aggregateType, ts.dataType, timestamps, values)
+			if err != nil {
+				return nil, err
+			}
+			row[j] = value
+			hasValue = true
+		}
+		if hasValue {
+			t.timestamps = append(t.timestamps, windowStart)
+			t.rows = append(t.rows, row)
+		}
+		windowStart = windowEnd
+	}
+	return t, nil
+}
+
+// aggregateResult 是聚合查询的结果，每个序列一个值，FIRST 和 LAST 还会返回对应的时间戳
+type aggregateResult struct {
+	paths      []string
+	tags       []map[string]string
+	types      []rpc.DataType
+	timestamps []int64
+	values     []interface{}
+}
+
+func (s *store) aggregateQuery(patterns []string, startTime, endTime int64, aggregateType rpc.AggregateType, tagFilter map[string][]string) (*aggregateResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var columns []*series
+	var types []rpc.DataType
+	var timestamps []int64
+	var values []interface{}
+	for _, ts := range s.match(patterns, tagFilter) {
+		seriesTimestamps := ts.timestamps(startTime, endTime)
+		if len(seriesTimestamps) == 0 {
+			continue
+		}
+		seriesValues := make([]interface{}, len(seriesTimestamps))
+		for i, timestamp := range seriesTimestamps {
+			seriesValues[i] = ts.points[timestamp]
+		}
+		dataType, value, timestamp, err := aggregate(aggregateType, ts.dataType, seriesTimestamps, seriesValues)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, ts)
+		types = append(types, dataType)
+		timestamps = append(timestamps, timestamp)
+		values = append(values, value)
+	}
+
+	t := newTable(columns)
+	ret := &aggregateResult{paths: t.paths, tags: t.tags, types: types, values: values}
+	if aggregateType == rpc.AggregateType_FIRST || aggregateType == rpc.AggregateType_LAST {
+		ret.timestamps = timestamps
+	}
+	return ret, nil
+}
+
+func (r *aggregateResult) encode() ([]byte, []byte, error) {
+	values, err := client.RowValuesToBytes(r.values, r.types)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.timestamps == nil {
+		return nil, values, nil
+	}
+	timestamps, err := client.TimestampsToBytes(r.timestamps)
+	if err != nil {
+		return nil, nil, err
+	}
+	return timestamps, values, nil
+}
+
+// last 返回每个序列在 [startTime, endTime) 内的最后一个点，每个序列一行
+func (s *store) last(patterns []string, startTime, endTime int64, tagFilter map[string][]string) *table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var columns []*series
+	var timestamps []int64
+	for _, ts := range s.match(patterns, tagFilter) {
+		if seriesTimestamps := ts.timestamps(startTime, endTime); len(seriesTimestamps) > 0 {
+			columns = append(columns, ts)
+			timestamps = append(timestamps, seriesTimestamps[len(seriesTimestamps)-1])
+		}
+	}
+
+	t := newTable(columns)
+	t.timestamps = timestamps
+	for j, ts := range columns {
+		row := make([]interface{}, len(columns))
+		row[j] = ts.points[timestamps[j]]
+		t.rows = append(t.rows, row)
+	}
+	return t
+}
+
+func (s *store) columns() ([]string, []map[string]string, []rpc.DataType) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t := newTable(s.match([]string{"*"}, nil))
+	return t.paths, t.tags, t.types
+}
+
+// encode 将结果编码为 QueryDataSet，每行的值只包含非空值，并由位图标记
+func (t *table) encode() (*rpc.QueryDataSet, error) {
+	timestamps, err := client.TimestampsToBytes(t.timestamps)
+	if err != nil {
+		return nil, err
+	}
+	valuesList, bitmapList, err := encodeRows(t.rows, t.types)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc.QueryDataSet{Timestamps: timestamps, ValuesList: valuesList, BitmapList: bitmapList}, nil
+}
+
+// encodeStream 将结果编码为流式查询的格式，时间戳作为第一列
+func (t *table) encodeStream() ([][]byte, [][]byte, error) {
+	types := append([]rpc.DataType{rpc.DataType_LONG}, t.types...)
+	rows := make([][]interface{}, len(t.rows))
+	for i, row := range t.rows {
+		rows[i] = append([]interface{}{t.timestamps[i]}, row...)
+	}
+	return encodeRows(rows, types)
+}
+
+func encodeRows(rows [][]interface{}, types []rpc.DataType) ([][]byte, [][]byte, error) {
+	valuesList := make([][]byte, 0, len(rows))
+	bitmapList := make([][]byte, 0, len(rows))
+	for _, row := range rows {
+		values, err := client.RowValuesToBytes(row, types)
+		if err != nil {
+			return nil, nil, err
+		}
+		bitmap := client.NewBitmap(len(types))
+		for j, value := range row {
+			if value != nil {
+				_ = bitmap.Mark(j)
+			}
+		}
+		valuesList = append(valuesList, values)
+		bitmapList = append(bitmapList, bitmap.GetBitmap())
+	}
+	return valuesList, bitmapList, nil
+}
+
+func sortInt64s(a []int64) {
+	sort.Slice(a, func(i, j int) bool {
+		return a[i] < a[j]
+	})
+}
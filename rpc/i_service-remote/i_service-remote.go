@@ -13,7 +13,7 @@ import (
 	"strconv"
 	"strings"
 	thrift "github.com/apache/thrift/lib/go/thrift"
-	"rpc"
+	"github.com/thulab/iginx-client-go/rpc"
 )
 
 var _ = rpc.GoUnusedProtection__
@@ -0,0 +1,86 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"strings"
+)
+
+// Condition 是 WHERE 子句中的值过滤条件
+type Condition interface {
+	build() (string, error)
+}
+
+type comparison struct {
+	path     string
+	operator string
+	value    interface{}
+}
+
+func (c comparison) build() (string, error) {
+	if err := checkPath(c.path, false); err != nil {
+		return "", err
+	}
+	value, err := FormatValue(c.value)
+	if err != nil {
+		return "", err
+	}
+	return c.path + " " + c.operator + " " + value, nil
+}
+
+func Eq(path string, value interface{}) Condition {
+	return comparison{path: path, operator: "=", value: value}
+}
+
+func Ne(path string, value interface{}) Condition {
+	return comparison{path: path, operator: "!=", value: value}
+}
+
+func Gt(path string, value interface{}) Condition {
+	return comparison{path: path, operator: ">", value: value}
+}
+
+func Ge(path string, value interface{}) Condition {
+	return comparison{path: path, operator: ">=", value: value}
+}
+
+func Lt(path string, value interface{}) Condition {
+	return comparison{path: path, operator: "<", value: value}
+}
+
+func Le(path string, value interface{}) Condition {
+	return comparison{path: path, operator: "<=", value: value}
+}
+
+type logical struct {
+	operator   string
+	conditions []Condition
+}
+
+func (l logical) build() (string, error) {
+	if len(l.conditions) == 0 {
+		return "", errors.New(l.operator + " needs at least one condition")
+	}
+	parts := make([]string, 0, len(l.conditions))
+	for _, condition := range l.conditions {
+		if condition == nil {
+			return "", errors.New("condition should not be nil")
+		}
+		part, err := condition.build()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := condition.(logical); ok && len(l.conditions) > 1 {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " "+l.operator+" "), nil
+}
+
+func And(conditions ...Condition) Condition {
+	return logical{operator: "AND", conditions: conditions}
+}
+
+func Or(conditions ...Condition) Condition {
+	return logical{operator: "OR", conditions: conditions}
+}
@@ -0,0 +1,158 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type insertRow struct {
+	timestamp int64
+	values    []interface{}
+}
+
+type InsertBuilder struct {
+	prefix  string
+	columns []string
+	tags    map[string]string
+	rows    []insertRow
+}
+
+// InsertInto 向 prefix 下的序列写入数据，对应 INSERT INTO prefix (TIMESTAMP, ...) VALUES ...
+func InsertInto(prefix string) *InsertBuilder {
+	return &InsertBuilder{prefix: prefix}
+}
+
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// WithTag 为写入的序列添加标签
+func (b *InsertBuilder) WithTag(key, value string) *InsertBuilder {
+	if b.tags == nil {
+		b.tags = make(map[string]string)
+	}
+	b.tags[key] = value
+	return b
+}
+
+// Values 添加一行数据，values 与 Columns 一一对应
+func (b *InsertBuilder) Values(timestamp int64, values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, insertRow{timestamp: timestamp, values: values})
+	return b
+}
+
+func (b *InsertBuilder) Build() (string, error) {
+	if err := checkPath(b.prefix, false); err != nil {
+		return "", err
+	}
+	if len(b.columns) == 0 {
+		return "", errors.New("insert needs at least one column")
+	}
+	if len(b.rows) == 0 {
+		return "", errors.New("insert needs at least one row")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + b.prefix)
+	if len(b.tags) > 0 {
+		parts := make([]string, 0, len(b.tags))
+		for _, k := range sortedKeys(b.tags) {
+			if b.tags[k] == "*" {
+				return "", errors.New("tag value of insert should not be *")
+			}
+			if err := checkTag(k, b.tags[k]); err != nil {
+				return "", err
+			}
+			parts = append(parts, k+"="+b.tags[k])
+		}
+		sb.WriteString("{" + strings.Join(parts, ", ") + "}")
+	}
+	sb.WriteString(" (TIMESTAMP")
+	for _, column := range b.columns {
+		if err := checkPath(column, false); err != nil {
+			return "", err
+		}
+		sb.WriteString(", " + column)
+	}
+	sb.WriteString(") VALUES ")
+	for i, row := range b.rows {
+		if len(row.values) != len(b.columns) {
+			return "", fmt.Errorf("row %d has %d values, but there are %d columns", i, len(row.values), len(b.columns))
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(" + strconv.FormatInt(row.timestamp, 10))
+		for _, value := range row.values {
+			literal, err := FormatValue(value)
+			if err != nil {
+				return "", fmt.Errorf("row %d: %v", i, err)
+			}
+			sb.WriteString(", " + literal)
+		}
+		sb.WriteString(")")
+	}
+	return sb.String(), nil
+}
+
+type DeleteBuilder struct {
+	paths     []string
+	hasTime   bool
+	startTime int64
+	endTime   int64
+	tags      map[string]string
+}
+
+// DeleteFrom 删除序列中的数据，路径可以包含通配符 *，例如 test.go.*
+func DeleteFrom(paths ...string) *DeleteBuilder {
+	return &DeleteBuilder{paths: paths}
+}
+
+// TimeRange 只删除 [startTime, endTime) 内的数据，未设置时删除所有数据
+func (b *DeleteBuilder) TimeRange(startTime, endTime int64) *DeleteBuilder {
+	b.hasTime = true
+	b.startTime = startTime
+	b.endTime = endTime
+	return b
+}
+
+func (b *DeleteBuilder) WithTag(key, value string) *DeleteBuilder {
+	if b.tags == nil {
+		b.tags = make(map[string]string)
+	}
+	b.tags[key] = value
+	return b
+}
+
+func (b *DeleteBuilder) Build() (string, error) {
+	if len(b.paths) == 0 {
+		return "", errors.New("delete needs at least one path")
+	}
+	for _, path := range b.paths {
+		if err := checkPath(path, true); err != nil {
+			return "", err
+		}
+	}
+	where, err := buildWhere(b.hasTime, b.startTime, b.endTime, nil)
+	if err != nil {
+		return "", err
+	}
+	with, err := buildWith(b.tags)
+	if err != nil {
+		return "", err
+	}
+	return "DELETE FROM " + strings.Join(b.paths, ", ") + where + with, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
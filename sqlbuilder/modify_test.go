@@ -0,0 +1,53 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thulab/iginx-client-go/sqlbuilder"
+)
+
+func TestInsert(t *testing.T) {
+	runGolden(t, []golden{
+		{
+			name: "rows",
+			builder: sqlbuilder.InsertInto("test.go").Columns("a", "b", "c").
+				Values(1, int64(1), "one", true).
+				Values(2, float32(2.5), []byte(`tw"o`), time.Unix(3, 0)),
+			want: `INSERT INTO test.go (TIMESTAMP, a, b, c) VALUES (1, 1, "one", true), (2, 2.5, "tw\"o", 3000)`,
+		},
+		{
+			name:    "tags are sorted",
+			builder: sqlbuilder.InsertInto("test").WithTag("k2", "v2").WithTag("k1", "v1").Columns("a").Values(1, 1),
+			want:    "INSERT INTO test{k1=v1, k2=v2} (TIMESTAMP, a) VALUES (1, 1)",
+		},
+		{name: "no columns", builder: sqlbuilder.InsertInto("test").Values(1), wantErr: true},
+		{name: "no rows", builder: sqlbuilder.InsertInto("test").Columns("a"), wantErr: true},
+		{name: "wildcard prefix", builder: sqlbuilder.InsertInto("test.*").Columns("a").Values(1, 1), wantErr: true},
+		{name: "wildcard column", builder: sqlbuilder.InsertInto("test").Columns("*").Values(1, 1), wantErr: true},
+		{name: "illegal column", builder: sqlbuilder.InsertInto("test").Columns("a)").Values(1, 1), wantErr: true},
+		{name: "wildcard tag", builder: sqlbuilder.InsertInto("test").WithTag("k", "*").Columns("a").Values(1, 1), wantErr: true},
+		{name: "value count", builder: sqlbuilder.InsertInto("test").Columns("a", "b").Values(1, 1), wantErr: true},
+		{name: "null value", builder: sqlbuilder.InsertInto("test").Columns("a").Values(1, nil), wantErr: true},
+		{name: "unsupported value", builder: sqlbuilder.InsertInto("test").Columns("a").Values(1, struct{}{}), wantErr: true},
+	})
+}
+
+func TestDelete(t *testing.T) {
+	runGolden(t, []golden{
+		{
+			name:    "paths",
+			builder: sqlbuilder.DeleteFrom("test.a", "test.*"),
+			want:    "DELETE FROM test.a, test.*",
+		},
+		{
+			name:    "time range and tags",
+			builder: sqlbuilder.DeleteFrom("test.a").TimeRange(-5, 5).WithTag("k", "v").WithTag("j", "*"),
+			want:    "DELETE FROM test.a WHERE TIME >= -5 AND TIME < 5 WITH j=* AND k=v",
+		},
+		{name: "no paths", builder: sqlbuilder.DeleteFrom(), wantErr: true},
+		{name: "illegal path", builder: sqlbuilder.DeleteFrom("test.a, b"), wantErr: true},
+		{name: "reversed time range", builder: sqlbuilder.DeleteFrom("test.a").TimeRange(5, 0), wantErr: true},
+		{name: "illegal tag key", builder: sqlbuilder.DeleteFrom("test.a").WithTag("k=1", "v"), wantErr: true},
+	})
+}
@@ -0,0 +1,121 @@
+// Package sqlbuilder 用于构造 IginX SQL 语句，避免手动拼接字符串：
+//
+//	sql, err := sqlbuilder.Select("a", "b").From("test.go").TimeRange(0, 10).Where(sqlbuilder.Gt("b", 6)).Build()
+//	// SELECT a, b FROM test.go WHERE TIME >= 0 AND TIME < 10 AND b > 6
+//
+// 路径和标签无法转义，含有非法字符时 Build 返回错误；字符串值会被加上引号并转义
+package sqlbuilder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNullValue = errors.New("iginx sql does not support null values")
+
+// QuoteString 将字符串转换为双引号包围的字符串字面量
+func QuoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// FormatValue 将 Go 的值转换为 SQL 字面量，time.Time 转换为毫秒时间戳
+func FormatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", ErrNullValue
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float32:
+		return formatFloat(float64(v), 32)
+	case float64:
+		return formatFloat(v, 64)
+	case string:
+		return QuoteString(v), nil
+	case []byte:
+		return QuoteString(string(v)), nil
+	case time.Time:
+		return strconv.FormatInt(v.UnixNano()/int64(time.Millisecond), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func formatFloat(v float64, bitSize int) (string, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", fmt.Errorf("unsupported float value %v", v)
+	}
+	s := strconv.FormatFloat(v, 'f', -1, bitSize)
+	// 没有小数点时会被解析为整数
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s, nil
+}
+
+func isNameChar(ch rune) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.ContainsRune("_-:@#$%&+~", ch)
+}
+
+// checkPath 检查路径是否只由合法的节点名组成，wildcard 为 true 时节点可以为 *
+func checkPath(path string, wildcard bool) error {
+	if path == "" {
+		return errors.New("path should not be empty")
+	}
+	for _, node := range strings.Split(path, ".") {
+		if node == "*" && wildcard {
+			continue
+		}
+		if err := checkName(node); err != nil {
+			return fmt.Errorf("invalid path %q: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func checkName(name string) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	for _, ch := range name {
+		if !isNameChar(ch) {
+			return fmt.Errorf("illegal character %q", ch)
+		}
+	}
+	return nil
+}
+
+func checkTag(key, value string) error {
+	if err := checkName(key); err != nil {
+		return fmt.Errorf("invalid tag key %q: %v", key, err)
+	}
+	if value == "*" {
+		return nil
+	}
+	if err := checkName(value); err != nil {
+		return fmt.Errorf("invalid tag value %q: %v", value, err)
+	}
+	return nil
+}
+
+func formatTimeRange(startTime, endTime int64) (string, error) {
+	if startTime >= endTime {
+		return "", errors.New("startTime should be less than endTime")
+	}
+	return "TIME >= " + strconv.FormatInt(startTime, 10) + " AND TIME < " + strconv.FormatInt(endTime, 10), nil
+}
@@ -0,0 +1,231 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+type selectItem struct {
+	path          string
+	aggregateType *rpc.AggregateType
+}
+
+type SelectBuilder struct {
+	items      []selectItem
+	from       string
+	hasTime    bool
+	startTime  int64
+	endTime    int64
+	conditions []Condition
+	tags       map[string]string
+
+	hasGroup   bool
+	groupStart int64
+	groupEnd   int64
+	precision  int64
+
+	limit     int64
+	offset    int64
+	orderBy   string
+	ascending bool
+}
+
+// Select 选择 FROM 前缀下的序列，路径可以包含通配符 *
+func Select(paths ...string) *SelectBuilder {
+	b := &SelectBuilder{ascending: true}
+	for _, path := range paths {
+		b.items = append(b.items, selectItem{path: path})
+	}
+	return b
+}
+
+// Aggregate 对序列进行聚合，例如 MAX(b)，与 GroupBy 一起使用时为降采样查询
+func (b *SelectBuilder) Aggregate(aggregateType rpc.AggregateType, paths ...string) *SelectBuilder {
+	for _, path := range paths {
+		t := aggregateType
+		b.items = append(b.items, selectItem{path: path, aggregateType: &t})
+	}
+	return b
+}
+
+func (b *SelectBuilder) From(prefix string) *SelectBuilder {
+	b.from = prefix
+	return b
+}
+
+// TimeRange 查询 [startTime, endTime) 内的数据
+func (b *SelectBuilder) TimeRange(startTime, endTime int64) *SelectBuilder {
+	b.hasTime = true
+	b.startTime = startTime
+	b.endTime = endTime
+	return b
+}
+
+// Where 添加值过滤条件，多次调用时条件之间为 AND 关系
+func (b *SelectBuilder) Where(conditions ...Condition) *SelectBuilder {
+	b.conditions = append(b.conditions, conditions...)
+	return b
+}
+
+// WithTag 只查询带有该标签的序列，value 为 * 时匹配任意值
+func (b *SelectBuilder) WithTag(key, value string) *SelectBuilder {
+	if b.tags == nil {
+		b.tags = make(map[string]string)
+	}
+	b.tags[key] = value
+	return b
+}
+
+func (b *SelectBuilder) WithTags(tags map[string]string) *SelectBuilder {
+	for k, v := range tags {
+		b.WithTag(k, v)
+	}
+	return b
+}
+
+// GroupBy 将 [startTime, endTime) 按 precision 毫秒分组进行降采样，对应 GROUP [startTime, endTime) BY precision ms
+func (b *SelectBuilder) GroupBy(startTime, endTime, precision int64) *SelectBuilder {
+	b.hasGroup = true
+	b.groupStart = startTime
+	b.groupEnd = endTime
+	b.precision = precision
+	return b
+}
+
+// Limit 为 0 时不限制
+func (b *SelectBuilder) Limit(limit int64) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+func (b *SelectBuilder) Offset(offset int64) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+func (b *SelectBuilder) OrderBy(path string, ascending bool) *SelectBuilder {
+	b.orderBy = path
+	b.ascending = ascending
+	return b
+}
+
+func (b *SelectBuilder) Build() (string, error) {
+	if len(b.items) == 0 {
+		return "", errors.New("select needs at least one path")
+	}
+	if err := checkPath(b.from, false); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	for i, item := range b.items {
+		if err := checkPath(item.path, true); err != nil {
+			return "", err
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if item.aggregateType != nil {
+			sb.WriteString(item.aggregateType.String() + "(" + item.path + ")")
+		} else {
+			sb.WriteString(item.path)
+		}
+	}
+	sb.WriteString(" FROM " + b.from)
+
+	where, err := buildWhere(b.hasTime, b.startTime, b.endTime, b.conditions)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(where)
+
+	with, err := buildWith(b.tags)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(with)
+
+	// IginX 中 GROUP BY 和 ORDER BY 不能同时使用
+	if b.hasGroup && b.orderBy != "" {
+		return "", errors.New("GROUP BY and ORDER BY can not be used together")
+	}
+	if b.hasGroup {
+		if b.groupStart >= b.groupEnd {
+			return "", errors.New("group startTime should be less than endTime")
+		}
+		if b.precision <= 0 {
+			return "", errors.New("precision should be positive")
+		}
+		sb.WriteString(" GROUP [" + strconv.FormatInt(b.groupStart, 10) + ", " + strconv.FormatInt(b.groupEnd, 10) + ") BY " + strconv.FormatInt(b.precision, 10) + "ms")
+	}
+	if b.orderBy != "" {
+		if err := checkPath(b.orderBy, false); err != nil {
+			return "", err
+		}
+		sb.WriteString(" ORDER BY " + b.orderBy)
+		if !b.ascending {
+			sb.WriteString(" DESC")
+		}
+	}
+
+	if b.limit < 0 || b.offset < 0 {
+		return "", errors.New("limit and offset should not be negative")
+	}
+	if b.offset > 0 && b.limit == 0 {
+		return "", errors.New("offset needs a limit")
+	}
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.FormatInt(b.limit, 10))
+		if b.offset > 0 {
+			sb.WriteString(" OFFSET " + strconv.FormatInt(b.offset, 10))
+		}
+	}
+	return sb.String(), nil
+}
+
+func buildWhere(hasTime bool, startTime, endTime int64, conditions []Condition) (string, error) {
+	var parts []string
+	if hasTime {
+		timeRange, err := formatTimeRange(startTime, endTime)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, timeRange)
+	}
+	for _, condition := range conditions {
+		if condition == nil {
+			return "", errors.New("condition should not be nil")
+		}
+		part, err := condition.build()
+		if err != nil {
+			return "", err
+		}
+		if l, ok := condition.(logical); ok && l.operator == "OR" && len(l.conditions) > 1 {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(parts, " AND "), nil
+}
+
+// buildWith 生成 WITH 子句，标签按键排序，多个标签之间为 AND 关系
+func buildWith(tags map[string]string) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+	parts := make([]string, 0, len(tags))
+	for _, k := range sortedKeys(tags) {
+		if err := checkTag(k, tags[k]); err != nil {
+			return "", err
+		}
+		parts = append(parts, k+"="+tags[k])
+	}
+	return " WITH " + strings.Join(parts, " AND "), nil
+}
@@ -0,0 +1,99 @@
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/thulab/iginx-client-go/rpc"
+	"github.com/thulab/iginx-client-go/sqlbuilder"
+)
+
+type builder interface {
+	Build() (string, error)
+}
+
+type golden struct {
+	name    string
+	builder builder
+	want    string
+	// wantErr 为 true 时 Build 应返回错误
+	wantErr bool
+}
+
+func runGolden(t *testing.T, tests []golden) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Build() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	runGolden(t, []golden{
+		{
+			name:    "paths",
+			builder: sqlbuilder.Select("a", "b.*").From("test.go"),
+			want:    "SELECT a, b.* FROM test.go",
+		},
+		{
+			name:    "time range and condition",
+			builder: sqlbuilder.Select("a", "b").From("test.go").TimeRange(0, 10).Where(sqlbuilder.Gt("b", 6)),
+			want:    "SELECT a, b FROM test.go WHERE TIME >= 0 AND TIME < 10 AND b > 6",
+		},
+		{
+			name: "nested conditions",
+			builder: sqlbuilder.Select("a").From("test").Where(
+				sqlbuilder.Or(sqlbuilder.Eq("a", "x\"y"), sqlbuilder.And(sqlbuilder.Ge("b", 1.5), sqlbuilder.Ne("c", true))),
+				sqlbuilder.Le("d", int32(3)),
+			),
+			want: `SELECT a FROM test WHERE (a = "x\"y" OR (b >= 1.5 AND c != true)) AND d <= 3`,
+		},
+		{
+			name:    "float without fraction",
+			builder: sqlbuilder.Select("a").From("test").Where(sqlbuilder.Lt("a", 2.0)),
+			want:    "SELECT a FROM test WHERE a < 2.0",
+		},
+		{
+			name:    "tag filters are sorted",
+			builder: sqlbuilder.Select("a").From("test").WithTag("k2", "*").WithTags(map[string]string{"k1": "v1"}),
+			want:    "SELECT a FROM test WITH k1=v1 AND k2=*",
+		},
+		{
+			name: "down sample",
+			builder: sqlbuilder.Select().Aggregate(rpc.AggregateType_MAX, "a", "b").From("test").
+				TimeRange(0, 100).WithTag("k", "v").GroupBy(0, 100, 10),
+			want: "SELECT MAX(a), MAX(b) FROM test WHERE TIME >= 0 AND TIME < 100 WITH k=v GROUP [0, 100) BY 10ms",
+		},
+		{
+			name:    "order by, limit and offset",
+			builder: sqlbuilder.Select("a").From("test").OrderBy("a", false).Limit(10).Offset(5),
+			want:    "SELECT a FROM test ORDER BY a DESC LIMIT 10 OFFSET 5",
+		},
+		{name: "no paths", builder: sqlbuilder.Select().From("test"), wantErr: true},
+		{name: "no prefix", builder: sqlbuilder.Select("a"), wantErr: true},
+		{name: "wildcard prefix", builder: sqlbuilder.Select("a").From("test.*"), wantErr: true},
+		{name: "illegal path", builder: sqlbuilder.Select("a b").From("test"), wantErr: true},
+		{name: "empty node", builder: sqlbuilder.Select("a..b").From("test"), wantErr: true},
+		{name: "injected path", builder: sqlbuilder.Select("a").From("test; DELETE FROM a"), wantErr: true},
+		{name: "illegal condition path", builder: sqlbuilder.Select("a").From("test").Where(sqlbuilder.Eq("a OR 1", 1)), wantErr: true},
+		{name: "null value", builder: sqlbuilder.Select("a").From("test").Where(sqlbuilder.Eq("a", nil)), wantErr: true},
+		{name: "empty time range", builder: sqlbuilder.Select("a").From("test").TimeRange(10, 10), wantErr: true},
+		{name: "illegal tag", builder: sqlbuilder.Select("a").From("test").WithTag("k", "v w"), wantErr: true},
+		{name: "empty or", builder: sqlbuilder.Select("a").From("test").Where(sqlbuilder.Or()), wantErr: true},
+		{name: "group by with order by", builder: sqlbuilder.Select("a").From("test").GroupBy(0, 10, 1).OrderBy("a", true), wantErr: true},
+		{name: "zero precision", builder: sqlbuilder.Select("a").From("test").GroupBy(0, 10, 0), wantErr: true},
+		{name: "offset without limit", builder: sqlbuilder.Select("a").From("test").Offset(5), wantErr: true},
+	})
+}
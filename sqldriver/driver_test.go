@@ -0,0 +1,71 @@
+package sqldriver_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/thulab/iginx-client-go/iginxtest"
+	"github.com/thulab/iginx-client-go/rpc"
+	"github.com/thulab/iginx-client-go/sqldriver"
+)
+
+func openDB(t *testing.T) (*iginxtest.Server, *sql.DB) {
+	t.Helper()
+	server, err := iginxtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	db := sql.OpenDB(sqldriver.NewConnector(&sqldriver.Config{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: "root",
+		Password: "root",
+	}))
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return server, db
+}
+
+func TestRowsAffectedNotAvailable(t *testing.T) {
+	_, db := openDB(t)
+	for _, query := range []string{
+		`INSERT INTO a (TIMESTAMP, b) VALUES (1, 1)`,
+		`COUNT POINTS`,
+	} {
+		result, err := db.Exec(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if _, err := result.RowsAffected(); !errors.Is(err, sqldriver.ErrRowsAffectedNotAvailable) {
+			t.Errorf("%s: RowsAffected err = %v, want %v", query, err, sqldriver.ErrRowsAffectedNotAvailable)
+		}
+	}
+}
+
+func TestQueryCountPoints(t *testing.T) {
+	server, db := openDB(t)
+	session, err := server.OpenSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	err = session.InsertRowRecords([]string{"a.b", "a.c"}, []int64{1, 2},
+		[][]interface{}{{int64(1), int64(2)}, {int64(3), nil}},
+		[]rpc.DataType{rpc.DataType_LONG, rpc.DataType_LONG}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var points int64
+	if err := db.QueryRow(`COUNT POINTS`).Scan(&points); err != nil {
+		t.Fatal(err)
+	}
+	if points != 3 {
+		t.Errorf("points = %d, want 3", points)
+	}
+}
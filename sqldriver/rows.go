@@ -0,0 +1,167 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+
+	"github.com/thulab/iginx-client-go/client"
+	"github.com/thulab/iginx-client-go/rpc"
+)
+
+var (
+	boolType    = reflect.TypeOf(false)
+	int32Type   = reflect.TypeOf(int32(0))
+	int64Type   = reflect.TypeOf(int64(0))
+	float32Type = reflect.TypeOf(float32(0))
+	float64Type = reflect.TypeOf(float64(0))
+	stringType  = reflect.TypeOf("")
+	anyType     = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// rows 按需从服务端拉取数据，每次拉取 fetch_size 行
+type rows struct {
+	ctx     context.Context
+	dataSet *client.StreamDataSet
+	columns []string
+	columnTypes
+	closed bool
+}
+
+func newRows(ctx context.Context, dataSet *client.StreamDataSet) *rows {
+	return &rows{
+		ctx:         ctx,
+		dataSet:     dataSet,
+		columns:     dataSet.GetColumns(),
+		columnTypes: dataSet.GetDataTypes(),
+	}
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.dataSet.Close()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.closed || !r.dataSet.HasMoreContext(r.ctx) {
+		if err := r.dataSet.Err(); err != nil {
+			return err
+		}
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	values := r.dataSet.NextRowContext(r.ctx)
+	for i := range dest {
+		if i >= len(values) {
+			dest[i] = nil
+			continue
+		}
+		dest[i] = driverValue(values[i])
+	}
+	return nil
+}
+
+// columnTypes 为结果中每一列的类型
+type columnTypes []rpc.DataType
+
+func (t columnTypes) ColumnTypeDatabaseTypeName(index int) string {
+	if index >= len(t) {
+		return ""
+	}
+	return t[index].String()
+}
+
+func (t columnTypes) ColumnTypeScanType(index int) reflect.Type {
+	if index >= len(t) {
+		return anyType
+	}
+	switch t[index] {
+	case rpc.DataType_BOOLEAN:
+		return boolType
+	case rpc.DataType_INTEGER:
+		return int32Type
+	case rpc.DataType_LONG:
+		return int64Type
+	case rpc.DataType_FLOAT:
+		return float32Type
+	case rpc.DataType_DOUBLE:
+		return float64Type
+	case rpc.DataType_BINARY:
+		return stringType
+	default:
+		return anyType
+	}
+}
+
+// ColumnTypeNullable 时间序列的值可能为空，所有列都是可空的
+func (t columnTypes) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return true, true
+}
+
+// sqlRows 为 ExecuteSQL 返回的结果，例如 COUNT POINTS 和 SHOW TIME SERIES，没有结果的语句返回空的 sqlRows
+type sqlRows struct {
+	columns []string
+	columnTypes
+	values [][]driver.Value
+	index  int
+}
+
+func newSQLRows(dataSet *client.SQLDataSet) *sqlRows {
+	r := &sqlRows{}
+	switch dataSet.Type {
+	case rpc.SqlType_CountPoints:
+		r.columns = []string{"points num"}
+		r.columnTypes = columnTypes{rpc.DataType_LONG}
+		r.values = [][]driver.Value{{dataSet.GetPointsNum()}}
+	case rpc.SqlType_GetReplicaNum:
+		r.columns = []string{"replica num"}
+		r.columnTypes = columnTypes{rpc.DataType_LONG}
+		r.values = [][]driver.Value{{int64(dataSet.GetReplicaNum())}}
+	case rpc.SqlType_ShowTimeSeries:
+		r.columns = []string{"path", "type"}
+		r.columnTypes = columnTypes{rpc.DataType_BINARY, rpc.DataType_BINARY}
+		for _, ts := range dataSet.GetTimeSeries() {
+			r.values = append(r.values, []driver.Value{ts.GetPath(), ts.GetType().String()})
+		}
+	}
+	return r
+}
+
+func (r *sqlRows) Columns() []string {
+	return r.columns
+}
+
+func (r *sqlRows) Close() error {
+	r.index = len(r.values)
+	return nil
+}
+
+func (r *sqlRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.index])
+	r.index++
+	return nil
+}
+
+// driverValue 将 INTEGER 和 FLOAT 的值转换为 database/sql 支持的类型
+func driverValue(value interface{}) driver.Value {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}